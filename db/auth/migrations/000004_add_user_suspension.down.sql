@@ -0,0 +1,10 @@
+ALTER TABLE activities
+    DROP COLUMN actor;
+
+DROP INDEX users_status_idx;
+
+ALTER TABLE users
+    DROP COLUMN suspended_at,
+    DROP COLUMN suspended_by,
+    DROP COLUMN suspension_reason,
+    DROP COLUMN status;
@@ -0,0 +1,10 @@
+ALTER TABLE users
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN suspension_reason TEXT,
+    ADD COLUMN suspended_by VARCHAR(64),
+    ADD COLUMN suspended_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX users_status_idx ON users (status);
+
+ALTER TABLE activities
+    ADD COLUMN actor VARCHAR(64);
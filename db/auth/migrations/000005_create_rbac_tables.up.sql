@@ -0,0 +1,42 @@
+CREATE TABLE roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE permissions (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE user_roles (
+    phone_number VARCHAR(15) NOT NULL REFERENCES users(phone_number) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    granted_by VARCHAR(64),
+    granted_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (phone_number, role)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Full administrative access'),
+    ('support', 'Customer support agent');
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'View user accounts and their state'),
+    ('users:suspend', 'Suspend and reinstate user accounts'),
+    ('roles:manage', 'Assign and revoke roles');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'users:read'),
+    ('admin', 'users:suspend'),
+    ('admin', 'roles:manage'),
+    ('support', 'users:read'),
+    ('support', 'users:suspend');
+
+ALTER TABLE activities
+    ADD COLUMN metadata JSONB;
@@ -0,0 +1,27 @@
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_tenant_id_phone_number_fkey;
+ALTER TABLE activities DROP CONSTRAINT activities_tenant_id_phone_number_fkey;
+ALTER TABLE otps DROP CONSTRAINT otps_tenant_id_phone_number_fkey;
+
+DELETE FROM user_roles WHERE tenant_id <> 'default';
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_pkey;
+ALTER TABLE user_roles DROP COLUMN tenant_id;
+ALTER TABLE user_roles ADD PRIMARY KEY (phone_number, role);
+
+DELETE FROM activities WHERE tenant_id <> 'default';
+ALTER TABLE activities DROP COLUMN tenant_id;
+
+DELETE FROM otps WHERE tenant_id <> 'default';
+ALTER TABLE otps DROP CONSTRAINT otps_pkey;
+ALTER TABLE otps DROP COLUMN tenant_id;
+ALTER TABLE otps ADD PRIMARY KEY (phone_number);
+
+DELETE FROM users WHERE tenant_id <> 'default';
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users DROP COLUMN tenant_id;
+ALTER TABLE users ADD PRIMARY KEY (phone_number);
+
+ALTER TABLE otps ADD FOREIGN KEY (phone_number) REFERENCES users(phone_number) ON DELETE CASCADE;
+ALTER TABLE activities ADD FOREIGN KEY (phone_number) REFERENCES users(phone_number) ON DELETE CASCADE;
+ALTER TABLE user_roles ADD FOREIGN KEY (phone_number) REFERENCES users(phone_number) ON DELETE CASCADE;
+
+DROP TABLE tenants;
@@ -0,0 +1,40 @@
+CREATE TABLE tenants (
+    id VARCHAR(64) PRIMARY KEY,
+    name TEXT NOT NULL,
+    api_key_hash CHAR(64) UNIQUE,
+    host VARCHAR(255) UNIQUE,
+    otp_length INT NOT NULL DEFAULT 8 CHECK (otp_length BETWEEN 4 AND 10),
+    otp_ttl_seconds INT NOT NULL DEFAULT 300 CHECK (otp_ttl_seconds > 0),
+    sms_sender VARCHAR(32) NOT NULL DEFAULT '',
+    sms_template TEXT NOT NULL DEFAULT 'Your verification code is {code}',
+    allowed_country_codes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Existing rows belong to the default tenant.
+INSERT INTO tenants (id, name) VALUES ('default', 'Default');
+
+ALTER TABLE otps DROP CONSTRAINT otps_phone_number_fkey;
+ALTER TABLE activities DROP CONSTRAINT activities_phone_number_fkey;
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_phone_number_fkey;
+
+ALTER TABLE users ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' REFERENCES tenants(id);
+ALTER TABLE users ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users ADD PRIMARY KEY (tenant_id, phone_number);
+
+ALTER TABLE otps ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE otps ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE otps DROP CONSTRAINT otps_pkey;
+ALTER TABLE otps ADD PRIMARY KEY (tenant_id, phone_number);
+ALTER TABLE otps ADD FOREIGN KEY (tenant_id, phone_number) REFERENCES users(tenant_id, phone_number) ON DELETE CASCADE;
+
+ALTER TABLE activities ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE activities ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE activities ADD FOREIGN KEY (tenant_id, phone_number) REFERENCES users(tenant_id, phone_number) ON DELETE CASCADE;
+
+ALTER TABLE user_roles ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE user_roles ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_pkey;
+ALTER TABLE user_roles ADD PRIMARY KEY (tenant_id, phone_number, role);
+ALTER TABLE user_roles ADD FOREIGN KEY (tenant_id, phone_number) REFERENCES users(tenant_id, phone_number) ON DELETE CASCADE;
@@ -0,0 +1,10 @@
+DELETE FROM permissions WHERE name = 'signup:manage';
+
+ALTER TABLE users
+    DROP COLUMN invite_code;
+
+DROP TABLE allowlist_entries;
+DROP TABLE invite_codes;
+
+ALTER TABLE tenants
+    DROP COLUMN signup_policy;
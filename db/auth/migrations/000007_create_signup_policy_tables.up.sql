@@ -0,0 +1,32 @@
+ALTER TABLE tenants
+    ADD COLUMN signup_policy VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (signup_policy IN ('open', 'invite_code', 'allowlist'));
+
+CREATE TABLE invite_codes (
+    tenant_id VARCHAR(64) NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    code VARCHAR(32) NOT NULL,
+    max_uses INT NOT NULL CHECK (max_uses > 0),
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_by VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, code)
+);
+
+CREATE TABLE allowlist_entries (
+    tenant_id VARCHAR(64) NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    entry VARCHAR(15) NOT NULL,
+    is_prefix BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, entry)
+);
+
+ALTER TABLE users
+    ADD COLUMN invite_code VARCHAR(32);
+
+INSERT INTO permissions (name, description) VALUES
+    ('signup:manage', 'Manage invite codes and the signup allowlist');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'signup:manage');
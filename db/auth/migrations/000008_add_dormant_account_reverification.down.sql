@@ -0,0 +1,10 @@
+DROP TABLE recovery_codes;
+
+ALTER TABLE tenants
+    DROP COLUMN dormancy_days;
+
+UPDATE users SET status = 'active' WHERE status = 'reverification_required';
+
+ALTER TABLE users
+    DROP COLUMN last_login_at,
+    ALTER COLUMN status TYPE VARCHAR(20);
@@ -0,0 +1,19 @@
+ALTER TABLE users
+    ALTER COLUMN status TYPE VARCHAR(32),
+    ADD COLUMN last_login_at TIMESTAMP WITH TIME ZONE;
+
+-- Zero disables dormancy checks for the tenant.
+ALTER TABLE tenants
+    ADD COLUMN dormancy_days INT NOT NULL DEFAULT 180 CHECK (dormancy_days >= 0);
+
+CREATE TABLE recovery_codes (
+    id SERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    phone_number VARCHAR(15) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tenant_id, phone_number) REFERENCES users(tenant_id, phone_number) ON DELETE CASCADE
+);
+
+CREATE INDEX recovery_codes_user_idx ON recovery_codes (tenant_id, phone_number);
@@ -0,0 +1,15 @@
+ALTER TABLE activities
+    DROP COLUMN failure_reason,
+    DROP COLUMN outcome,
+    DROP COLUMN request_id,
+    DROP COLUMN device_id,
+    DROP COLUMN user_agent,
+    DROP COLUMN ip;
+
+DELETE FROM activities a
+WHERE NOT EXISTS (
+    SELECT 1 FROM users u WHERE u.tenant_id = a.tenant_id AND u.phone_number = a.phone_number
+);
+
+ALTER TABLE activities
+    ADD FOREIGN KEY (tenant_id, phone_number) REFERENCES users(tenant_id, phone_number) ON DELETE CASCADE;
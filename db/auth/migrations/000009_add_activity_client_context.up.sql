@@ -0,0 +1,11 @@
+-- Failed attempts are recorded for numbers that have no account, so
+-- activities no longer reference users.
+ALTER TABLE activities DROP CONSTRAINT activities_tenant_id_phone_number_fkey;
+
+ALTER TABLE activities
+    ADD COLUMN ip INET,
+    ADD COLUMN user_agent TEXT,
+    ADD COLUMN device_id VARCHAR(128),
+    ADD COLUMN request_id VARCHAR(64),
+    ADD COLUMN outcome VARCHAR(10) NOT NULL DEFAULT 'success' CHECK (outcome IN ('success', 'failure')),
+    ADD COLUMN failure_reason TEXT;
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE name = 'activities:read';
+
+DROP INDEX activities_user_type_timestamp_idx;
+DROP INDEX activities_user_timestamp_idx;
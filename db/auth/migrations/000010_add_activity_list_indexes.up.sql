@@ -0,0 +1,10 @@
+-- Listings are ordered newest first and resumed by (timestamp, id).
+CREATE INDEX activities_user_timestamp_idx ON activities (tenant_id, phone_number, timestamp DESC, id DESC);
+CREATE INDEX activities_user_type_timestamp_idx ON activities (tenant_id, phone_number, type, timestamp DESC, id DESC);
+
+INSERT INTO permissions (name, description) VALUES
+    ('activities:read', 'View the activity history of any user');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'activities:read'),
+    ('support', 'activities:read');
@@ -0,0 +1,6 @@
+DROP INDEX activities_chain_idx;
+
+ALTER TABLE activities
+    DROP COLUMN hash_version,
+    DROP COLUMN hash,
+    DROP COLUMN prev_hash;
@@ -0,0 +1,8 @@
+-- Each activity is chained to the previous activity of the same user.
+-- Rows recorded before this migration stay unhashed.
+ALTER TABLE activities
+    ADD COLUMN prev_hash CHAR(64),
+    ADD COLUMN hash CHAR(64),
+    ADD COLUMN hash_version SMALLINT;
+
+CREATE INDEX activities_chain_idx ON activities (tenant_id, phone_number, id);
@@ -0,0 +1,3 @@
+DROP TABLE login_alerts;
+DROP TABLE known_networks;
+DROP TABLE known_devices;
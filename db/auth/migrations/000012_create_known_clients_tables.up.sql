@@ -0,0 +1,37 @@
+CREATE TABLE known_devices (
+    tenant_id VARCHAR(64) NOT NULL,
+    phone_number VARCHAR(15) NOT NULL,
+    device_id VARCHAR(128) NOT NULL,
+    user_agent TEXT,
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, phone_number, device_id),
+    FOREIGN KEY (tenant_id, phone_number) REFERENCES users(tenant_id, phone_number) ON DELETE CASCADE
+);
+
+CREATE TABLE known_networks (
+    tenant_id VARCHAR(64) NOT NULL,
+    phone_number VARCHAR(15) NOT NULL,
+    network CIDR NOT NULL,
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, phone_number, network),
+    FOREIGN KEY (tenant_id, phone_number) REFERENCES users(tenant_id, phone_number) ON DELETE CASCADE
+);
+
+CREATE TABLE login_alerts (
+    id VARCHAR(16) NOT NULL,
+    tenant_id VARCHAR(64) NOT NULL,
+    phone_number VARCHAR(15) NOT NULL,
+    device_id VARCHAR(128),
+    user_agent TEXT,
+    ip INET,
+    network CIDR,
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    new_network BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'rejected')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, phone_number) REFERENCES users(tenant_id, phone_number) ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+DROP INDEX users_unverified_created_at_idx;
+DROP INDEX otps_expiration_idx;
+DROP INDEX activities_timestamp_idx;
+
+DROP TABLE activity_chain_anchors;
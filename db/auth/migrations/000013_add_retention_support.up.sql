@@ -0,0 +1,13 @@
+-- When retention purges the oldest activities of a chain, the last purged
+-- activity is kept here so the rest of the chain still verifies.
+CREATE TABLE activity_chain_anchors (
+    tenant_id VARCHAR(64) NOT NULL,
+    phone_number VARCHAR(15) NOT NULL,
+    activity_id INT NOT NULL,
+    hash VARCHAR(64) NOT NULL DEFAULT '',
+    PRIMARY KEY (tenant_id, phone_number)
+);
+
+CREATE INDEX activities_timestamp_idx ON activities (timestamp);
+CREATE INDEX otps_expiration_idx ON otps (expiration);
+CREATE INDEX users_unverified_created_at_idx ON users (created_at) WHERE NOT verified;
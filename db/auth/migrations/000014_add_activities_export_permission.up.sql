@@ -0,0 +1,5 @@
+INSERT INTO permissions (name, description) VALUES
+    ('activities:export', 'Export the activity log of the tenant');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('admin', 'activities:export');
@@ -0,0 +1,6 @@
+DROP INDEX activities_ip_idx;
+
+ALTER TABLE activities
+    DROP COLUMN risk_signals,
+    DROP COLUMN risk_decision,
+    DROP COLUMN risk_score;
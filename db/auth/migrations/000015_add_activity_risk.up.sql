@@ -0,0 +1,7 @@
+ALTER TABLE activities
+    ADD COLUMN risk_score SMALLINT,
+    ADD COLUMN risk_decision VARCHAR(10) CHECK (risk_decision IN ('allow', 'challenge', 'deny')),
+    ADD COLUMN risk_signals JSONB;
+
+-- Counts login attempts per IP address for the IP velocity rule
+CREATE INDEX activities_ip_idx ON activities (tenant_id, ip, timestamp) WHERE ip IS NOT NULL;
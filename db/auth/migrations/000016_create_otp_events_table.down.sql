@@ -0,0 +1,3 @@
+DROP TABLE otp_events;
+
+ALTER TABLE otps DROP COLUMN id;
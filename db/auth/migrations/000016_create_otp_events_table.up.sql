@@ -0,0 +1,17 @@
+ALTER TABLE otps ADD COLUMN id VARCHAR(32);
+
+-- Append-only ledger of every step of an OTP's life. It outlives both the
+-- OTP and the user, so it has no foreign keys.
+CREATE TABLE otp_events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    otp_id VARCHAR(32) NOT NULL,
+    phone_number VARCHAR(15) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    detail TEXT,
+    source VARCHAR(10) NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX otp_events_phone_idx ON otp_events (tenant_id, phone_number, timestamp);
+CREATE INDEX otp_events_timestamp_idx ON otp_events (timestamp);
@@ -0,0 +1,16 @@
+-- Queue of the Postgres message broker, used instead of RabbitMQ by small
+-- deployments. Claimed messages stay invisible until visible_at; messages
+-- that kept failing are kept with dead_at set.
+CREATE TABLE broker_messages (
+    id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(128) NOT NULL,
+    payload BYTEA NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    visible_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    dead_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX broker_messages_ready_idx ON broker_messages (topic, visible_at, id) WHERE dead_at IS NULL;
+CREATE INDEX broker_messages_dead_idx ON broker_messages (topic, id) WHERE dead_at IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE broker_messages DROP COLUMN headers;
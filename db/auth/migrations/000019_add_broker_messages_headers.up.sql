@@ -0,0 +1,2 @@
+-- CloudEvents attributes of each queued message, keyed by attribute name.
+ALTER TABLE broker_messages ADD COLUMN headers JSONB NOT NULL DEFAULT '{}';
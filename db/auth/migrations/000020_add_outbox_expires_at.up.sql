@@ -0,0 +1,3 @@
+-- Messages not published by then are dropped, and published ones carry it to
+-- the broker as their expiry.
+ALTER TABLE outbox ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
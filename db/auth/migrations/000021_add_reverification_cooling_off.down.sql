@@ -0,0 +1,5 @@
+ALTER TABLE users
+    DROP COLUMN reverification_required_at;
+
+ALTER TABLE tenants
+    DROP COLUMN reverification_cooling_off_hours;
@@ -0,0 +1,9 @@
+-- Users without recovery codes may reverify with the SMS OTP alone once this
+-- long has passed since the account was demoted.
+ALTER TABLE tenants
+    ADD COLUMN reverification_cooling_off_hours INT NOT NULL DEFAULT 72 CHECK (reverification_cooling_off_hours >= 0);
+
+ALTER TABLE users
+    ADD COLUMN reverification_required_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE users SET reverification_required_at = updated_at WHERE status = 'reverification_required';
@@ -0,0 +1,5 @@
+DROP INDEX outbox_dead_idx;
+DROP INDEX outbox_pending_idx;
+CREATE INDEX outbox_pending_idx ON outbox (next_attempt_at, id) WHERE sent_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN dead_at;
@@ -0,0 +1,6 @@
+-- Messages that keep failing are parked here instead of being retried forever.
+ALTER TABLE outbox ADD COLUMN dead_at TIMESTAMP WITH TIME ZONE;
+
+DROP INDEX outbox_pending_idx;
+CREATE INDEX outbox_pending_idx ON outbox (next_attempt_at, id) WHERE sent_at IS NULL AND dead_at IS NULL;
+CREATE INDEX outbox_dead_idx ON outbox (dead_at) WHERE dead_at IS NOT NULL;
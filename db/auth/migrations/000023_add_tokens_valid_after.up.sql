@@ -0,0 +1,3 @@
+-- Access tokens of the user issued up to this time are refused.
+ALTER TABLE users
+    ADD COLUMN tokens_valid_after TIMESTAMP WITH TIME ZONE;
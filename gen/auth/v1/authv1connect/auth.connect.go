@@ -47,6 +47,53 @@ const (
 	AuthServiceValidatePhoneNumberLoginProcedure = "/auth.v1.AuthService/ValidatePhoneNumberLogin"
 	// AuthServiceGetProfileProcedure is the fully-qualified name of the AuthService's GetProfile RPC.
 	AuthServiceGetProfileProcedure = "/auth.v1.AuthService/GetProfile"
+	// AuthServiceGenerateRecoveryCodesProcedure is the fully-qualified name of the AuthService's
+	// GenerateRecoveryCodes RPC.
+	AuthServiceGenerateRecoveryCodesProcedure = "/auth.v1.AuthService/GenerateRecoveryCodes"
+	// AuthServiceListActivitiesProcedure is the fully-qualified name of the AuthService's
+	// ListActivities RPC.
+	AuthServiceListActivitiesProcedure = "/auth.v1.AuthService/ListActivities"
+	// AuthServiceConfirmLoginProcedure is the fully-qualified name of the AuthService's ConfirmLogin
+	// RPC.
+	AuthServiceConfirmLoginProcedure = "/auth.v1.AuthService/ConfirmLogin"
+	// AuthServiceRejectLoginProcedure is the fully-qualified name of the AuthService's RejectLogin RPC.
+	AuthServiceRejectLoginProcedure = "/auth.v1.AuthService/RejectLogin"
+	// AuthServiceSuspendUserProcedure is the fully-qualified name of the AuthService's SuspendUser RPC.
+	AuthServiceSuspendUserProcedure = "/auth.v1.AuthService/SuspendUser"
+	// AuthServiceReinstateUserProcedure is the fully-qualified name of the AuthService's ReinstateUser
+	// RPC.
+	AuthServiceReinstateUserProcedure = "/auth.v1.AuthService/ReinstateUser"
+	// AuthServiceListSuspendedUsersProcedure is the fully-qualified name of the AuthService's
+	// ListSuspendedUsers RPC.
+	AuthServiceListSuspendedUsersProcedure = "/auth.v1.AuthService/ListSuspendedUsers"
+	// AuthServiceAssignRoleProcedure is the fully-qualified name of the AuthService's AssignRole RPC.
+	AuthServiceAssignRoleProcedure = "/auth.v1.AuthService/AssignRole"
+	// AuthServiceRevokeRoleProcedure is the fully-qualified name of the AuthService's RevokeRole RPC.
+	AuthServiceRevokeRoleProcedure = "/auth.v1.AuthService/RevokeRole"
+	// AuthServiceListUserRolesProcedure is the fully-qualified name of the AuthService's ListUserRoles
+	// RPC.
+	AuthServiceListUserRolesProcedure = "/auth.v1.AuthService/ListUserRoles"
+	// AuthServiceCreateInviteCodeProcedure is the fully-qualified name of the AuthService's
+	// CreateInviteCode RPC.
+	AuthServiceCreateInviteCodeProcedure = "/auth.v1.AuthService/CreateInviteCode"
+	// AuthServiceListInviteCodesProcedure is the fully-qualified name of the AuthService's
+	// ListInviteCodes RPC.
+	AuthServiceListInviteCodesProcedure = "/auth.v1.AuthService/ListInviteCodes"
+	// AuthServiceAddAllowlistEntryProcedure is the fully-qualified name of the AuthService's
+	// AddAllowlistEntry RPC.
+	AuthServiceAddAllowlistEntryProcedure = "/auth.v1.AuthService/AddAllowlistEntry"
+	// AuthServiceRemoveAllowlistEntryProcedure is the fully-qualified name of the AuthService's
+	// RemoveAllowlistEntry RPC.
+	AuthServiceRemoveAllowlistEntryProcedure = "/auth.v1.AuthService/RemoveAllowlistEntry"
+	// AuthServiceListAllowlistEntriesProcedure is the fully-qualified name of the AuthService's
+	// ListAllowlistEntries RPC.
+	AuthServiceListAllowlistEntriesProcedure = "/auth.v1.AuthService/ListAllowlistEntries"
+	// AuthServiceExportActivitiesProcedure is the fully-qualified name of the AuthService's
+	// ExportActivities RPC.
+	AuthServiceExportActivitiesProcedure = "/auth.v1.AuthService/ExportActivities"
+	// AuthServiceGetOTPTimelineProcedure is the fully-qualified name of the AuthService's
+	// GetOTPTimeline RPC.
+	AuthServiceGetOTPTimelineProcedure = "/auth.v1.AuthService/GetOTPTimeline"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
@@ -57,6 +104,23 @@ var (
 	authServiceLoginInitiateMethodDescriptor            = authServiceServiceDescriptor.Methods().ByName("LoginInitiate")
 	authServiceValidatePhoneNumberLoginMethodDescriptor = authServiceServiceDescriptor.Methods().ByName("ValidatePhoneNumberLogin")
 	authServiceGetProfileMethodDescriptor               = authServiceServiceDescriptor.Methods().ByName("GetProfile")
+	authServiceGenerateRecoveryCodesMethodDescriptor    = authServiceServiceDescriptor.Methods().ByName("GenerateRecoveryCodes")
+	authServiceListActivitiesMethodDescriptor           = authServiceServiceDescriptor.Methods().ByName("ListActivities")
+	authServiceConfirmLoginMethodDescriptor             = authServiceServiceDescriptor.Methods().ByName("ConfirmLogin")
+	authServiceRejectLoginMethodDescriptor              = authServiceServiceDescriptor.Methods().ByName("RejectLogin")
+	authServiceSuspendUserMethodDescriptor              = authServiceServiceDescriptor.Methods().ByName("SuspendUser")
+	authServiceReinstateUserMethodDescriptor            = authServiceServiceDescriptor.Methods().ByName("ReinstateUser")
+	authServiceListSuspendedUsersMethodDescriptor       = authServiceServiceDescriptor.Methods().ByName("ListSuspendedUsers")
+	authServiceAssignRoleMethodDescriptor               = authServiceServiceDescriptor.Methods().ByName("AssignRole")
+	authServiceRevokeRoleMethodDescriptor               = authServiceServiceDescriptor.Methods().ByName("RevokeRole")
+	authServiceListUserRolesMethodDescriptor            = authServiceServiceDescriptor.Methods().ByName("ListUserRoles")
+	authServiceCreateInviteCodeMethodDescriptor         = authServiceServiceDescriptor.Methods().ByName("CreateInviteCode")
+	authServiceListInviteCodesMethodDescriptor          = authServiceServiceDescriptor.Methods().ByName("ListInviteCodes")
+	authServiceAddAllowlistEntryMethodDescriptor        = authServiceServiceDescriptor.Methods().ByName("AddAllowlistEntry")
+	authServiceRemoveAllowlistEntryMethodDescriptor     = authServiceServiceDescriptor.Methods().ByName("RemoveAllowlistEntry")
+	authServiceListAllowlistEntriesMethodDescriptor     = authServiceServiceDescriptor.Methods().ByName("ListAllowlistEntries")
+	authServiceExportActivitiesMethodDescriptor         = authServiceServiceDescriptor.Methods().ByName("ExportActivities")
+	authServiceGetOTPTimelineMethodDescriptor           = authServiceServiceDescriptor.Methods().ByName("GetOTPTimeline")
 )
 
 // AuthServiceClient is a client for the auth.v1.AuthService service.
@@ -66,6 +130,24 @@ type AuthServiceClient interface {
 	LoginInitiate(context.Context, *connect.Request[v1.LoginInitiateRequest]) (*connect.Response[v1.LoginInitiateResponse], error)
 	ValidatePhoneNumberLogin(context.Context, *connect.Request[v1.ValidatePhoneNumberLoginRequest]) (*connect.Response[v1.ValidatePhoneNumberLoginResponse], error)
 	GetProfile(context.Context, *connect.Request[v1.GetProfileRequest]) (*connect.Response[v1.GetProfileResponse], error)
+	GenerateRecoveryCodes(context.Context, *connect.Request[v1.GenerateRecoveryCodesRequest]) (*connect.Response[v1.GenerateRecoveryCodesResponse], error)
+	ListActivities(context.Context, *connect.Request[v1.ListActivitiesRequest]) (*connect.Response[v1.ListActivitiesResponse], error)
+	ConfirmLogin(context.Context, *connect.Request[v1.ConfirmLoginRequest]) (*connect.Response[v1.ConfirmLoginResponse], error)
+	RejectLogin(context.Context, *connect.Request[v1.RejectLoginRequest]) (*connect.Response[v1.RejectLoginResponse], error)
+	// Admin
+	SuspendUser(context.Context, *connect.Request[v1.SuspendUserRequest]) (*connect.Response[v1.SuspendUserResponse], error)
+	ReinstateUser(context.Context, *connect.Request[v1.ReinstateUserRequest]) (*connect.Response[v1.ReinstateUserResponse], error)
+	ListSuspendedUsers(context.Context, *connect.Request[v1.ListSuspendedUsersRequest]) (*connect.Response[v1.ListSuspendedUsersResponse], error)
+	AssignRole(context.Context, *connect.Request[v1.AssignRoleRequest]) (*connect.Response[v1.AssignRoleResponse], error)
+	RevokeRole(context.Context, *connect.Request[v1.RevokeRoleRequest]) (*connect.Response[v1.RevokeRoleResponse], error)
+	ListUserRoles(context.Context, *connect.Request[v1.ListUserRolesRequest]) (*connect.Response[v1.ListUserRolesResponse], error)
+	CreateInviteCode(context.Context, *connect.Request[v1.CreateInviteCodeRequest]) (*connect.Response[v1.CreateInviteCodeResponse], error)
+	ListInviteCodes(context.Context, *connect.Request[v1.ListInviteCodesRequest]) (*connect.Response[v1.ListInviteCodesResponse], error)
+	AddAllowlistEntry(context.Context, *connect.Request[v1.AddAllowlistEntryRequest]) (*connect.Response[v1.AddAllowlistEntryResponse], error)
+	RemoveAllowlistEntry(context.Context, *connect.Request[v1.RemoveAllowlistEntryRequest]) (*connect.Response[v1.RemoveAllowlistEntryResponse], error)
+	ListAllowlistEntries(context.Context, *connect.Request[v1.ListAllowlistEntriesRequest]) (*connect.Response[v1.ListAllowlistEntriesResponse], error)
+	ExportActivities(context.Context, *connect.Request[v1.ExportActivitiesRequest]) (*connect.ServerStreamForClient[v1.ExportActivitiesResponse], error)
+	GetOTPTimeline(context.Context, *connect.Request[v1.GetOTPTimelineRequest]) (*connect.Response[v1.GetOTPTimelineResponse], error)
 }
 
 // NewAuthServiceClient constructs a client for the auth.v1.AuthService service. By default, it uses
@@ -108,6 +190,108 @@ func NewAuthServiceClient(httpClient connect.HTTPClient, baseURL string, opts ..
 			connect.WithSchema(authServiceGetProfileMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		generateRecoveryCodes: connect.NewClient[v1.GenerateRecoveryCodesRequest, v1.GenerateRecoveryCodesResponse](
+			httpClient,
+			baseURL+AuthServiceGenerateRecoveryCodesProcedure,
+			connect.WithSchema(authServiceGenerateRecoveryCodesMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		listActivities: connect.NewClient[v1.ListActivitiesRequest, v1.ListActivitiesResponse](
+			httpClient,
+			baseURL+AuthServiceListActivitiesProcedure,
+			connect.WithSchema(authServiceListActivitiesMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		confirmLogin: connect.NewClient[v1.ConfirmLoginRequest, v1.ConfirmLoginResponse](
+			httpClient,
+			baseURL+AuthServiceConfirmLoginProcedure,
+			connect.WithSchema(authServiceConfirmLoginMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		rejectLogin: connect.NewClient[v1.RejectLoginRequest, v1.RejectLoginResponse](
+			httpClient,
+			baseURL+AuthServiceRejectLoginProcedure,
+			connect.WithSchema(authServiceRejectLoginMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		suspendUser: connect.NewClient[v1.SuspendUserRequest, v1.SuspendUserResponse](
+			httpClient,
+			baseURL+AuthServiceSuspendUserProcedure,
+			connect.WithSchema(authServiceSuspendUserMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		reinstateUser: connect.NewClient[v1.ReinstateUserRequest, v1.ReinstateUserResponse](
+			httpClient,
+			baseURL+AuthServiceReinstateUserProcedure,
+			connect.WithSchema(authServiceReinstateUserMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		listSuspendedUsers: connect.NewClient[v1.ListSuspendedUsersRequest, v1.ListSuspendedUsersResponse](
+			httpClient,
+			baseURL+AuthServiceListSuspendedUsersProcedure,
+			connect.WithSchema(authServiceListSuspendedUsersMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		assignRole: connect.NewClient[v1.AssignRoleRequest, v1.AssignRoleResponse](
+			httpClient,
+			baseURL+AuthServiceAssignRoleProcedure,
+			connect.WithSchema(authServiceAssignRoleMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		revokeRole: connect.NewClient[v1.RevokeRoleRequest, v1.RevokeRoleResponse](
+			httpClient,
+			baseURL+AuthServiceRevokeRoleProcedure,
+			connect.WithSchema(authServiceRevokeRoleMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		listUserRoles: connect.NewClient[v1.ListUserRolesRequest, v1.ListUserRolesResponse](
+			httpClient,
+			baseURL+AuthServiceListUserRolesProcedure,
+			connect.WithSchema(authServiceListUserRolesMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		createInviteCode: connect.NewClient[v1.CreateInviteCodeRequest, v1.CreateInviteCodeResponse](
+			httpClient,
+			baseURL+AuthServiceCreateInviteCodeProcedure,
+			connect.WithSchema(authServiceCreateInviteCodeMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		listInviteCodes: connect.NewClient[v1.ListInviteCodesRequest, v1.ListInviteCodesResponse](
+			httpClient,
+			baseURL+AuthServiceListInviteCodesProcedure,
+			connect.WithSchema(authServiceListInviteCodesMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		addAllowlistEntry: connect.NewClient[v1.AddAllowlistEntryRequest, v1.AddAllowlistEntryResponse](
+			httpClient,
+			baseURL+AuthServiceAddAllowlistEntryProcedure,
+			connect.WithSchema(authServiceAddAllowlistEntryMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		removeAllowlistEntry: connect.NewClient[v1.RemoveAllowlistEntryRequest, v1.RemoveAllowlistEntryResponse](
+			httpClient,
+			baseURL+AuthServiceRemoveAllowlistEntryProcedure,
+			connect.WithSchema(authServiceRemoveAllowlistEntryMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		listAllowlistEntries: connect.NewClient[v1.ListAllowlistEntriesRequest, v1.ListAllowlistEntriesResponse](
+			httpClient,
+			baseURL+AuthServiceListAllowlistEntriesProcedure,
+			connect.WithSchema(authServiceListAllowlistEntriesMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		exportActivities: connect.NewClient[v1.ExportActivitiesRequest, v1.ExportActivitiesResponse](
+			httpClient,
+			baseURL+AuthServiceExportActivitiesProcedure,
+			connect.WithSchema(authServiceExportActivitiesMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		getOTPTimeline: connect.NewClient[v1.GetOTPTimelineRequest, v1.GetOTPTimelineResponse](
+			httpClient,
+			baseURL+AuthServiceGetOTPTimelineProcedure,
+			connect.WithSchema(authServiceGetOTPTimelineMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
@@ -118,6 +302,23 @@ type authServiceClient struct {
 	loginInitiate            *connect.Client[v1.LoginInitiateRequest, v1.LoginInitiateResponse]
 	validatePhoneNumberLogin *connect.Client[v1.ValidatePhoneNumberLoginRequest, v1.ValidatePhoneNumberLoginResponse]
 	getProfile               *connect.Client[v1.GetProfileRequest, v1.GetProfileResponse]
+	generateRecoveryCodes    *connect.Client[v1.GenerateRecoveryCodesRequest, v1.GenerateRecoveryCodesResponse]
+	listActivities           *connect.Client[v1.ListActivitiesRequest, v1.ListActivitiesResponse]
+	confirmLogin             *connect.Client[v1.ConfirmLoginRequest, v1.ConfirmLoginResponse]
+	rejectLogin              *connect.Client[v1.RejectLoginRequest, v1.RejectLoginResponse]
+	suspendUser              *connect.Client[v1.SuspendUserRequest, v1.SuspendUserResponse]
+	reinstateUser            *connect.Client[v1.ReinstateUserRequest, v1.ReinstateUserResponse]
+	listSuspendedUsers       *connect.Client[v1.ListSuspendedUsersRequest, v1.ListSuspendedUsersResponse]
+	assignRole               *connect.Client[v1.AssignRoleRequest, v1.AssignRoleResponse]
+	revokeRole               *connect.Client[v1.RevokeRoleRequest, v1.RevokeRoleResponse]
+	listUserRoles            *connect.Client[v1.ListUserRolesRequest, v1.ListUserRolesResponse]
+	createInviteCode         *connect.Client[v1.CreateInviteCodeRequest, v1.CreateInviteCodeResponse]
+	listInviteCodes          *connect.Client[v1.ListInviteCodesRequest, v1.ListInviteCodesResponse]
+	addAllowlistEntry        *connect.Client[v1.AddAllowlistEntryRequest, v1.AddAllowlistEntryResponse]
+	removeAllowlistEntry     *connect.Client[v1.RemoveAllowlistEntryRequest, v1.RemoveAllowlistEntryResponse]
+	listAllowlistEntries     *connect.Client[v1.ListAllowlistEntriesRequest, v1.ListAllowlistEntriesResponse]
+	exportActivities         *connect.Client[v1.ExportActivitiesRequest, v1.ExportActivitiesResponse]
+	getOTPTimeline           *connect.Client[v1.GetOTPTimelineRequest, v1.GetOTPTimelineResponse]
 }
 
 // SignUpWithPhoneNumber calls auth.v1.AuthService.SignUpWithPhoneNumber.
@@ -145,6 +346,91 @@ func (c *authServiceClient) GetProfile(ctx context.Context, req *connect.Request
 	return c.getProfile.CallUnary(ctx, req)
 }
 
+// GenerateRecoveryCodes calls auth.v1.AuthService.GenerateRecoveryCodes.
+func (c *authServiceClient) GenerateRecoveryCodes(ctx context.Context, req *connect.Request[v1.GenerateRecoveryCodesRequest]) (*connect.Response[v1.GenerateRecoveryCodesResponse], error) {
+	return c.generateRecoveryCodes.CallUnary(ctx, req)
+}
+
+// ListActivities calls auth.v1.AuthService.ListActivities.
+func (c *authServiceClient) ListActivities(ctx context.Context, req *connect.Request[v1.ListActivitiesRequest]) (*connect.Response[v1.ListActivitiesResponse], error) {
+	return c.listActivities.CallUnary(ctx, req)
+}
+
+// ConfirmLogin calls auth.v1.AuthService.ConfirmLogin.
+func (c *authServiceClient) ConfirmLogin(ctx context.Context, req *connect.Request[v1.ConfirmLoginRequest]) (*connect.Response[v1.ConfirmLoginResponse], error) {
+	return c.confirmLogin.CallUnary(ctx, req)
+}
+
+// RejectLogin calls auth.v1.AuthService.RejectLogin.
+func (c *authServiceClient) RejectLogin(ctx context.Context, req *connect.Request[v1.RejectLoginRequest]) (*connect.Response[v1.RejectLoginResponse], error) {
+	return c.rejectLogin.CallUnary(ctx, req)
+}
+
+// SuspendUser calls auth.v1.AuthService.SuspendUser.
+func (c *authServiceClient) SuspendUser(ctx context.Context, req *connect.Request[v1.SuspendUserRequest]) (*connect.Response[v1.SuspendUserResponse], error) {
+	return c.suspendUser.CallUnary(ctx, req)
+}
+
+// ReinstateUser calls auth.v1.AuthService.ReinstateUser.
+func (c *authServiceClient) ReinstateUser(ctx context.Context, req *connect.Request[v1.ReinstateUserRequest]) (*connect.Response[v1.ReinstateUserResponse], error) {
+	return c.reinstateUser.CallUnary(ctx, req)
+}
+
+// ListSuspendedUsers calls auth.v1.AuthService.ListSuspendedUsers.
+func (c *authServiceClient) ListSuspendedUsers(ctx context.Context, req *connect.Request[v1.ListSuspendedUsersRequest]) (*connect.Response[v1.ListSuspendedUsersResponse], error) {
+	return c.listSuspendedUsers.CallUnary(ctx, req)
+}
+
+// AssignRole calls auth.v1.AuthService.AssignRole.
+func (c *authServiceClient) AssignRole(ctx context.Context, req *connect.Request[v1.AssignRoleRequest]) (*connect.Response[v1.AssignRoleResponse], error) {
+	return c.assignRole.CallUnary(ctx, req)
+}
+
+// RevokeRole calls auth.v1.AuthService.RevokeRole.
+func (c *authServiceClient) RevokeRole(ctx context.Context, req *connect.Request[v1.RevokeRoleRequest]) (*connect.Response[v1.RevokeRoleResponse], error) {
+	return c.revokeRole.CallUnary(ctx, req)
+}
+
+// ListUserRoles calls auth.v1.AuthService.ListUserRoles.
+func (c *authServiceClient) ListUserRoles(ctx context.Context, req *connect.Request[v1.ListUserRolesRequest]) (*connect.Response[v1.ListUserRolesResponse], error) {
+	return c.listUserRoles.CallUnary(ctx, req)
+}
+
+// CreateInviteCode calls auth.v1.AuthService.CreateInviteCode.
+func (c *authServiceClient) CreateInviteCode(ctx context.Context, req *connect.Request[v1.CreateInviteCodeRequest]) (*connect.Response[v1.CreateInviteCodeResponse], error) {
+	return c.createInviteCode.CallUnary(ctx, req)
+}
+
+// ListInviteCodes calls auth.v1.AuthService.ListInviteCodes.
+func (c *authServiceClient) ListInviteCodes(ctx context.Context, req *connect.Request[v1.ListInviteCodesRequest]) (*connect.Response[v1.ListInviteCodesResponse], error) {
+	return c.listInviteCodes.CallUnary(ctx, req)
+}
+
+// AddAllowlistEntry calls auth.v1.AuthService.AddAllowlistEntry.
+func (c *authServiceClient) AddAllowlistEntry(ctx context.Context, req *connect.Request[v1.AddAllowlistEntryRequest]) (*connect.Response[v1.AddAllowlistEntryResponse], error) {
+	return c.addAllowlistEntry.CallUnary(ctx, req)
+}
+
+// RemoveAllowlistEntry calls auth.v1.AuthService.RemoveAllowlistEntry.
+func (c *authServiceClient) RemoveAllowlistEntry(ctx context.Context, req *connect.Request[v1.RemoveAllowlistEntryRequest]) (*connect.Response[v1.RemoveAllowlistEntryResponse], error) {
+	return c.removeAllowlistEntry.CallUnary(ctx, req)
+}
+
+// ListAllowlistEntries calls auth.v1.AuthService.ListAllowlistEntries.
+func (c *authServiceClient) ListAllowlistEntries(ctx context.Context, req *connect.Request[v1.ListAllowlistEntriesRequest]) (*connect.Response[v1.ListAllowlistEntriesResponse], error) {
+	return c.listAllowlistEntries.CallUnary(ctx, req)
+}
+
+// ExportActivities calls auth.v1.AuthService.ExportActivities.
+func (c *authServiceClient) ExportActivities(ctx context.Context, req *connect.Request[v1.ExportActivitiesRequest]) (*connect.ServerStreamForClient[v1.ExportActivitiesResponse], error) {
+	return c.exportActivities.CallServerStream(ctx, req)
+}
+
+// GetOTPTimeline calls auth.v1.AuthService.GetOTPTimeline.
+func (c *authServiceClient) GetOTPTimeline(ctx context.Context, req *connect.Request[v1.GetOTPTimelineRequest]) (*connect.Response[v1.GetOTPTimelineResponse], error) {
+	return c.getOTPTimeline.CallUnary(ctx, req)
+}
+
 // AuthServiceHandler is an implementation of the auth.v1.AuthService service.
 type AuthServiceHandler interface {
 	SignUpWithPhoneNumber(context.Context, *connect.Request[v1.SignUpWithPhoneNumberRequest]) (*connect.Response[v1.SignUpWithPhoneNumberResponse], error)
@@ -152,6 +438,24 @@ type AuthServiceHandler interface {
 	LoginInitiate(context.Context, *connect.Request[v1.LoginInitiateRequest]) (*connect.Response[v1.LoginInitiateResponse], error)
 	ValidatePhoneNumberLogin(context.Context, *connect.Request[v1.ValidatePhoneNumberLoginRequest]) (*connect.Response[v1.ValidatePhoneNumberLoginResponse], error)
 	GetProfile(context.Context, *connect.Request[v1.GetProfileRequest]) (*connect.Response[v1.GetProfileResponse], error)
+	GenerateRecoveryCodes(context.Context, *connect.Request[v1.GenerateRecoveryCodesRequest]) (*connect.Response[v1.GenerateRecoveryCodesResponse], error)
+	ListActivities(context.Context, *connect.Request[v1.ListActivitiesRequest]) (*connect.Response[v1.ListActivitiesResponse], error)
+	ConfirmLogin(context.Context, *connect.Request[v1.ConfirmLoginRequest]) (*connect.Response[v1.ConfirmLoginResponse], error)
+	RejectLogin(context.Context, *connect.Request[v1.RejectLoginRequest]) (*connect.Response[v1.RejectLoginResponse], error)
+	// Admin
+	SuspendUser(context.Context, *connect.Request[v1.SuspendUserRequest]) (*connect.Response[v1.SuspendUserResponse], error)
+	ReinstateUser(context.Context, *connect.Request[v1.ReinstateUserRequest]) (*connect.Response[v1.ReinstateUserResponse], error)
+	ListSuspendedUsers(context.Context, *connect.Request[v1.ListSuspendedUsersRequest]) (*connect.Response[v1.ListSuspendedUsersResponse], error)
+	AssignRole(context.Context, *connect.Request[v1.AssignRoleRequest]) (*connect.Response[v1.AssignRoleResponse], error)
+	RevokeRole(context.Context, *connect.Request[v1.RevokeRoleRequest]) (*connect.Response[v1.RevokeRoleResponse], error)
+	ListUserRoles(context.Context, *connect.Request[v1.ListUserRolesRequest]) (*connect.Response[v1.ListUserRolesResponse], error)
+	CreateInviteCode(context.Context, *connect.Request[v1.CreateInviteCodeRequest]) (*connect.Response[v1.CreateInviteCodeResponse], error)
+	ListInviteCodes(context.Context, *connect.Request[v1.ListInviteCodesRequest]) (*connect.Response[v1.ListInviteCodesResponse], error)
+	AddAllowlistEntry(context.Context, *connect.Request[v1.AddAllowlistEntryRequest]) (*connect.Response[v1.AddAllowlistEntryResponse], error)
+	RemoveAllowlistEntry(context.Context, *connect.Request[v1.RemoveAllowlistEntryRequest]) (*connect.Response[v1.RemoveAllowlistEntryResponse], error)
+	ListAllowlistEntries(context.Context, *connect.Request[v1.ListAllowlistEntriesRequest]) (*connect.Response[v1.ListAllowlistEntriesResponse], error)
+	ExportActivities(context.Context, *connect.Request[v1.ExportActivitiesRequest], *connect.ServerStream[v1.ExportActivitiesResponse]) error
+	GetOTPTimeline(context.Context, *connect.Request[v1.GetOTPTimelineRequest]) (*connect.Response[v1.GetOTPTimelineResponse], error)
 }
 
 // NewAuthServiceHandler builds an HTTP handler from the service implementation. It returns the path
@@ -190,6 +494,108 @@ func NewAuthServiceHandler(svc AuthServiceHandler, opts ...connect.HandlerOption
 		connect.WithSchema(authServiceGetProfileMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	authServiceGenerateRecoveryCodesHandler := connect.NewUnaryHandler(
+		AuthServiceGenerateRecoveryCodesProcedure,
+		svc.GenerateRecoveryCodes,
+		connect.WithSchema(authServiceGenerateRecoveryCodesMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceListActivitiesHandler := connect.NewUnaryHandler(
+		AuthServiceListActivitiesProcedure,
+		svc.ListActivities,
+		connect.WithSchema(authServiceListActivitiesMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceConfirmLoginHandler := connect.NewUnaryHandler(
+		AuthServiceConfirmLoginProcedure,
+		svc.ConfirmLogin,
+		connect.WithSchema(authServiceConfirmLoginMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceRejectLoginHandler := connect.NewUnaryHandler(
+		AuthServiceRejectLoginProcedure,
+		svc.RejectLogin,
+		connect.WithSchema(authServiceRejectLoginMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceSuspendUserHandler := connect.NewUnaryHandler(
+		AuthServiceSuspendUserProcedure,
+		svc.SuspendUser,
+		connect.WithSchema(authServiceSuspendUserMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceReinstateUserHandler := connect.NewUnaryHandler(
+		AuthServiceReinstateUserProcedure,
+		svc.ReinstateUser,
+		connect.WithSchema(authServiceReinstateUserMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceListSuspendedUsersHandler := connect.NewUnaryHandler(
+		AuthServiceListSuspendedUsersProcedure,
+		svc.ListSuspendedUsers,
+		connect.WithSchema(authServiceListSuspendedUsersMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceAssignRoleHandler := connect.NewUnaryHandler(
+		AuthServiceAssignRoleProcedure,
+		svc.AssignRole,
+		connect.WithSchema(authServiceAssignRoleMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceRevokeRoleHandler := connect.NewUnaryHandler(
+		AuthServiceRevokeRoleProcedure,
+		svc.RevokeRole,
+		connect.WithSchema(authServiceRevokeRoleMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceListUserRolesHandler := connect.NewUnaryHandler(
+		AuthServiceListUserRolesProcedure,
+		svc.ListUserRoles,
+		connect.WithSchema(authServiceListUserRolesMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceCreateInviteCodeHandler := connect.NewUnaryHandler(
+		AuthServiceCreateInviteCodeProcedure,
+		svc.CreateInviteCode,
+		connect.WithSchema(authServiceCreateInviteCodeMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceListInviteCodesHandler := connect.NewUnaryHandler(
+		AuthServiceListInviteCodesProcedure,
+		svc.ListInviteCodes,
+		connect.WithSchema(authServiceListInviteCodesMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceAddAllowlistEntryHandler := connect.NewUnaryHandler(
+		AuthServiceAddAllowlistEntryProcedure,
+		svc.AddAllowlistEntry,
+		connect.WithSchema(authServiceAddAllowlistEntryMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceRemoveAllowlistEntryHandler := connect.NewUnaryHandler(
+		AuthServiceRemoveAllowlistEntryProcedure,
+		svc.RemoveAllowlistEntry,
+		connect.WithSchema(authServiceRemoveAllowlistEntryMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceListAllowlistEntriesHandler := connect.NewUnaryHandler(
+		AuthServiceListAllowlistEntriesProcedure,
+		svc.ListAllowlistEntries,
+		connect.WithSchema(authServiceListAllowlistEntriesMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceExportActivitiesHandler := connect.NewServerStreamHandler(
+		AuthServiceExportActivitiesProcedure,
+		svc.ExportActivities,
+		connect.WithSchema(authServiceExportActivitiesMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceGetOTPTimelineHandler := connect.NewUnaryHandler(
+		AuthServiceGetOTPTimelineProcedure,
+		svc.GetOTPTimeline,
+		connect.WithSchema(authServiceGetOTPTimelineMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/auth.v1.AuthService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case AuthServiceSignUpWithPhoneNumberProcedure:
@@ -202,6 +608,40 @@ func NewAuthServiceHandler(svc AuthServiceHandler, opts ...connect.HandlerOption
 			authServiceValidatePhoneNumberLoginHandler.ServeHTTP(w, r)
 		case AuthServiceGetProfileProcedure:
 			authServiceGetProfileHandler.ServeHTTP(w, r)
+		case AuthServiceGenerateRecoveryCodesProcedure:
+			authServiceGenerateRecoveryCodesHandler.ServeHTTP(w, r)
+		case AuthServiceListActivitiesProcedure:
+			authServiceListActivitiesHandler.ServeHTTP(w, r)
+		case AuthServiceConfirmLoginProcedure:
+			authServiceConfirmLoginHandler.ServeHTTP(w, r)
+		case AuthServiceRejectLoginProcedure:
+			authServiceRejectLoginHandler.ServeHTTP(w, r)
+		case AuthServiceSuspendUserProcedure:
+			authServiceSuspendUserHandler.ServeHTTP(w, r)
+		case AuthServiceReinstateUserProcedure:
+			authServiceReinstateUserHandler.ServeHTTP(w, r)
+		case AuthServiceListSuspendedUsersProcedure:
+			authServiceListSuspendedUsersHandler.ServeHTTP(w, r)
+		case AuthServiceAssignRoleProcedure:
+			authServiceAssignRoleHandler.ServeHTTP(w, r)
+		case AuthServiceRevokeRoleProcedure:
+			authServiceRevokeRoleHandler.ServeHTTP(w, r)
+		case AuthServiceListUserRolesProcedure:
+			authServiceListUserRolesHandler.ServeHTTP(w, r)
+		case AuthServiceCreateInviteCodeProcedure:
+			authServiceCreateInviteCodeHandler.ServeHTTP(w, r)
+		case AuthServiceListInviteCodesProcedure:
+			authServiceListInviteCodesHandler.ServeHTTP(w, r)
+		case AuthServiceAddAllowlistEntryProcedure:
+			authServiceAddAllowlistEntryHandler.ServeHTTP(w, r)
+		case AuthServiceRemoveAllowlistEntryProcedure:
+			authServiceRemoveAllowlistEntryHandler.ServeHTTP(w, r)
+		case AuthServiceListAllowlistEntriesProcedure:
+			authServiceListAllowlistEntriesHandler.ServeHTTP(w, r)
+		case AuthServiceExportActivitiesProcedure:
+			authServiceExportActivitiesHandler.ServeHTTP(w, r)
+		case AuthServiceGetOTPTimelineProcedure:
+			authServiceGetOTPTimelineHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -230,3 +670,71 @@ func (UnimplementedAuthServiceHandler) ValidatePhoneNumberLogin(context.Context,
 func (UnimplementedAuthServiceHandler) GetProfile(context.Context, *connect.Request[v1.GetProfileRequest]) (*connect.Response[v1.GetProfileResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.GetProfile is not implemented"))
 }
+
+func (UnimplementedAuthServiceHandler) GenerateRecoveryCodes(context.Context, *connect.Request[v1.GenerateRecoveryCodesRequest]) (*connect.Response[v1.GenerateRecoveryCodesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.GenerateRecoveryCodes is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ListActivities(context.Context, *connect.Request[v1.ListActivitiesRequest]) (*connect.Response[v1.ListActivitiesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.ListActivities is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ConfirmLogin(context.Context, *connect.Request[v1.ConfirmLoginRequest]) (*connect.Response[v1.ConfirmLoginResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.ConfirmLogin is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) RejectLogin(context.Context, *connect.Request[v1.RejectLoginRequest]) (*connect.Response[v1.RejectLoginResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.RejectLogin is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) SuspendUser(context.Context, *connect.Request[v1.SuspendUserRequest]) (*connect.Response[v1.SuspendUserResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.SuspendUser is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ReinstateUser(context.Context, *connect.Request[v1.ReinstateUserRequest]) (*connect.Response[v1.ReinstateUserResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.ReinstateUser is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ListSuspendedUsers(context.Context, *connect.Request[v1.ListSuspendedUsersRequest]) (*connect.Response[v1.ListSuspendedUsersResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.ListSuspendedUsers is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) AssignRole(context.Context, *connect.Request[v1.AssignRoleRequest]) (*connect.Response[v1.AssignRoleResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.AssignRole is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) RevokeRole(context.Context, *connect.Request[v1.RevokeRoleRequest]) (*connect.Response[v1.RevokeRoleResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.RevokeRole is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ListUserRoles(context.Context, *connect.Request[v1.ListUserRolesRequest]) (*connect.Response[v1.ListUserRolesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.ListUserRoles is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) CreateInviteCode(context.Context, *connect.Request[v1.CreateInviteCodeRequest]) (*connect.Response[v1.CreateInviteCodeResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.CreateInviteCode is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ListInviteCodes(context.Context, *connect.Request[v1.ListInviteCodesRequest]) (*connect.Response[v1.ListInviteCodesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.ListInviteCodes is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) AddAllowlistEntry(context.Context, *connect.Request[v1.AddAllowlistEntryRequest]) (*connect.Response[v1.AddAllowlistEntryResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.AddAllowlistEntry is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) RemoveAllowlistEntry(context.Context, *connect.Request[v1.RemoveAllowlistEntryRequest]) (*connect.Response[v1.RemoveAllowlistEntryResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.RemoveAllowlistEntry is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ListAllowlistEntries(context.Context, *connect.Request[v1.ListAllowlistEntriesRequest]) (*connect.Response[v1.ListAllowlistEntriesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.ListAllowlistEntries is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ExportActivities(context.Context, *connect.Request[v1.ExportActivitiesRequest], *connect.ServerStream[v1.ExportActivitiesResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.ExportActivities is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) GetOTPTimeline(context.Context, *connect.Request[v1.GetOTPTimelineRequest]) (*connect.Response[v1.GetOTPTimelineResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("auth.v1.AuthService.GetOTPTimeline is not implemented"))
+}
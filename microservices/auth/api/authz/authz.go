@@ -0,0 +1,213 @@
+// Package authz enforces role-based permissions on the auth service's
+// Connect and REST transports.
+package authz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+
+	"midaslabs/gen/auth/v1/authv1connect"
+	"midaslabs/microservices/auth/internal/domain"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticated marks procedures open to any authenticated caller, such as
+// self-service procedures acting on the caller's own account.
+const Authenticated domain.Permission = ""
+
+// ProcedurePermissions declares the permission required to call each
+// procedure. Procedures that are not listed are public.
+var ProcedurePermissions = map[string]domain.Permission{
+	authv1connect.AuthServiceGetProfileProcedure:            Authenticated,
+	authv1connect.AuthServiceGenerateRecoveryCodesProcedure: Authenticated,
+	authv1connect.AuthServiceListActivitiesProcedure:        Authenticated,
+	authv1connect.AuthServiceConfirmLoginProcedure:          Authenticated,
+	authv1connect.AuthServiceRejectLoginProcedure:           Authenticated,
+
+	authv1connect.AuthServiceSuspendUserProcedure:          domain.PermissionUsersSuspend,
+	authv1connect.AuthServiceReinstateUserProcedure:        domain.PermissionUsersSuspend,
+	authv1connect.AuthServiceListSuspendedUsersProcedure:   domain.PermissionUsersRead,
+	authv1connect.AuthServiceAssignRoleProcedure:           domain.PermissionRolesManage,
+	authv1connect.AuthServiceRevokeRoleProcedure:           domain.PermissionRolesManage,
+	authv1connect.AuthServiceListUserRolesProcedure:        domain.PermissionUsersRead,
+	authv1connect.AuthServiceCreateInviteCodeProcedure:     domain.PermissionSignupManage,
+	authv1connect.AuthServiceListInviteCodesProcedure:      domain.PermissionSignupManage,
+	authv1connect.AuthServiceAddAllowlistEntryProcedure:    domain.PermissionSignupManage,
+	authv1connect.AuthServiceRemoveAllowlistEntryProcedure: domain.PermissionSignupManage,
+	authv1connect.AuthServiceListAllowlistEntriesProcedure: domain.PermissionSignupManage,
+	authv1connect.AuthServiceExportActivitiesProcedure:     domain.PermissionActivitiesExport,
+	authv1connect.AuthServiceGetOTPTimelineProcedure:       domain.PermissionActivitiesRead,
+}
+
+// RESTProcedures maps each REST route onto the procedure it fronts, so both
+// transports share ProcedurePermissions.
+var RESTProcedures = map[string]string{
+	"/signup":                  authv1connect.AuthServiceSignUpWithPhoneNumberProcedure,
+	"/signup/verify":           authv1connect.AuthServiceVerifyPhoneNumberProcedure,
+	"/login/initiate":          authv1connect.AuthServiceLoginInitiateProcedure,
+	"/login/complete":          authv1connect.AuthServiceValidatePhoneNumberLoginProcedure,
+	"/profile":                 authv1connect.AuthServiceGetProfileProcedure,
+	"/profile/recovery-codes":  authv1connect.AuthServiceGenerateRecoveryCodesProcedure,
+	"/activities":              authv1connect.AuthServiceListActivitiesProcedure,
+	"/login/confirm":           authv1connect.AuthServiceConfirmLoginProcedure,
+	"/login/reject":            authv1connect.AuthServiceRejectLoginProcedure,
+	"/admin/users/suspend":     authv1connect.AuthServiceSuspendUserProcedure,
+	"/admin/users/reinstate":   authv1connect.AuthServiceReinstateUserProcedure,
+	"/admin/users/suspended":   authv1connect.AuthServiceListSuspendedUsersProcedure,
+	"/admin/roles/assign":      authv1connect.AuthServiceAssignRoleProcedure,
+	"/admin/roles/revoke":      authv1connect.AuthServiceRevokeRoleProcedure,
+	"/admin/roles/list":        authv1connect.AuthServiceListUserRolesProcedure,
+	"/admin/invites/create":    authv1connect.AuthServiceCreateInviteCodeProcedure,
+	"/admin/invites":           authv1connect.AuthServiceListInviteCodesProcedure,
+	"/admin/allowlist/add":     authv1connect.AuthServiceAddAllowlistEntryProcedure,
+	"/admin/allowlist/remove":  authv1connect.AuthServiceRemoveAllowlistEntryProcedure,
+	"/admin/allowlist":         authv1connect.AuthServiceListAllowlistEntriesProcedure,
+	"/admin/activities/export": authv1connect.AuthServiceExportActivitiesProcedure,
+	"/admin/otps/timeline":     authv1connect.AuthServiceGetOTPTimelineProcedure,
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx carrying the verified claims of the
+// caller.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the verified claims of the caller, if any.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// SubjectFromContext returns the authenticated caller, if any.
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok || claims.Subject == "" {
+		return "", false
+	}
+	return claims.Subject, true
+}
+
+// ResolveUser returns the user a self-service procedure acts on: the caller
+// when phoneNumber is empty or the caller's own, and another user only when
+// the caller's token grants permission. It returns ErrUnauthenticated without
+// a caller and domain.ErrPermissionDenied otherwise.
+func ResolveUser(ctx context.Context, phoneNumber string, permission domain.Permission) (string, error) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+	if phoneNumber == "" || phoneNumber == claims.Subject {
+		return claims.Subject, nil
+	}
+	if !claims.HasPermission(permission) {
+		return "", domain.ErrPermissionDenied
+	}
+	return phoneNumber, nil
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(header http.Header) string {
+	scheme, token, ok := strings.Cut(header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// authorize attaches the caller of a verified bearer token to ctx and checks
+// the token's permission claims against the permission required by
+// procedure. An invalid token is rejected even on public procedures.
+func authorize(ctx context.Context, tokens *Tokens, procedure string, header http.Header) (context.Context, error) {
+	var claims *Claims
+	if token := bearerToken(header); token != "" {
+		var err error
+		if claims, err = tokens.Verify(ctx, token); err != nil {
+			return ctx, err
+		}
+		ctx = WithClaims(ctx, claims)
+	}
+
+	permission, ok := ProcedurePermissions[procedure]
+	if !ok {
+		return ctx, nil
+	}
+	if claims == nil {
+		return ctx, ErrUnauthenticated
+	}
+	if permission == Authenticated || claims.HasPermission(permission) {
+		return ctx, nil
+	}
+	return ctx, domain.ErrPermissionDenied
+}
+
+// NewInterceptor returns a Connect interceptor enforcing ProcedurePermissions
+// on unary and streaming procedures.
+func NewInterceptor(tokens *Tokens) connect.Interceptor {
+	return &interceptor{tokens: tokens}
+}
+
+type interceptor struct {
+	tokens *Tokens
+}
+
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		ctx, err := authorize(ctx, i.tokens, req.Spec().Procedure, req.Header())
+		if err != nil {
+			return nil, connectError(err)
+		}
+		return next(ctx, req)
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := authorize(ctx, i.tokens, conn.Spec().Procedure, conn.RequestHeader())
+		if err != nil {
+			return connectError(err)
+		}
+		return next(ctx, conn)
+	}
+}
+
+func connectError(err error) error {
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		return connect.NewError(connect.CodeUnauthenticated, err)
+	case errors.Is(err, domain.ErrPermissionDenied):
+		return connect.NewError(connect.CodePermissionDenied, err)
+	default:
+		return connect.NewError(connect.CodeInternal, err)
+	}
+}
+
+// Middleware returns net/http middleware enforcing ProcedurePermissions on
+// the routes listed in RESTProcedures.
+func Middleware(tokens *Tokens) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, err := authorize(r.Context(), tokens, RESTProcedures[r.URL.Path], r.Header)
+			switch {
+			case err == nil:
+				next.ServeHTTP(w, r.WithContext(ctx))
+			case errors.Is(err, ErrUnauthenticated):
+				http.Error(w, "Unauthenticated", http.StatusUnauthorized)
+			case errors.Is(err, domain.ErrPermissionDenied):
+				http.Error(w, "Permission denied", http.StatusForbidden)
+			default:
+				http.Error(w, "Failed to authorize request", http.StatusInternalServerError)
+			}
+		})
+	}
+}
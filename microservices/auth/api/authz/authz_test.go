@@ -0,0 +1,223 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"midaslabs/microservices/auth/internal/domain"
+)
+
+// userSource holds users and the roles they hold.
+type userSource struct {
+	users map[string]*domain.User
+	roles map[string][]*domain.Role
+}
+
+func (s *userSource) GetUser(ctx context.Context, phoneNumber string) (*domain.User, error) {
+	user, ok := s.users[phoneNumber]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (s *userSource) GetUserRoles(ctx context.Context, phoneNumber string) ([]*domain.Role, error) {
+	return s.roles[phoneNumber], nil
+}
+
+func newUserSource() *userSource {
+	return &userSource{
+		users: map[string]*domain.User{
+			"+15550199": domain.NewUser("acme", "+15550199"),
+			"+15550100": domain.NewUser("acme", "+15550100"),
+		},
+		roles: map[string][]*domain.Role{
+			"+15550199": {{Name: "admin", Permissions: []domain.Permission{domain.PermissionUsersSuspend, domain.PermissionUsersRead}}},
+		},
+	}
+}
+
+var users = newUserSource()
+
+func tenantContext(id string) context.Context {
+	return domain.WithTenant(context.Background(), &domain.Tenant{ID: id})
+}
+
+func newTokens(t *testing.T, ttl time.Duration) *Tokens {
+	t.Helper()
+	tokens, err := NewTokens([]byte("0123456789abcdef0123456789abcdef"), ttl, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func issue(t *testing.T, tokens *Tokens, tenantID, phoneNumber string) string {
+	t.Helper()
+	token, _, err := tokens.Issue(tenantContext(tenantID), phoneNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestMiddleware(t *testing.T) {
+	tokens := newTokens(t, time.Minute)
+	admin := issue(t, tokens, "acme", "+15550199")
+	user := issue(t, tokens, "acme", "+15550100")
+	otherTenant := issue(t, tokens, "globex", "+15550199")
+	expired := issue(t, newTokens(t, -time.Minute), "acme", "+15550199")
+	otherKey, err := NewTokens([]byte("fedcba9876543210fedcba9876543210"), time.Minute, users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := issue(t, otherKey, "acme", "+15550199")
+	// {"alg":"none"} with the admin's claims
+	unsigned := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." + admin[len("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9."):len(admin)-43]
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		want   int
+	}{
+		{"public without token", "/signup", nil, http.StatusOK},
+		{"admin without token", "/admin/users/suspend", nil, http.StatusUnauthorized},
+		{"subject header is ignored", "/admin/users/suspend", http.Header{"X-Auth-Subject": {"+15550199"}}, http.StatusUnauthorized},
+		{"admin token", "/admin/users/suspend", bearer(admin), http.StatusOK},
+		{"token without permission", "/admin/users/suspend", bearer(user), http.StatusForbidden},
+		{"self-service with user token", "/activities", bearer(user), http.StatusOK},
+		{"token of another tenant", "/admin/users/suspend", bearer(otherTenant), http.StatusUnauthorized},
+		{"expired token", "/admin/users/suspend", bearer(expired), http.StatusUnauthorized},
+		{"token signed with another key", "/admin/users/suspend", bearer(forged), http.StatusUnauthorized},
+		{"unsigned token", "/admin/users/suspend", bearer(unsigned), http.StatusUnauthorized},
+		{"invalid token on public route", "/signup", bearer("garbage"), http.StatusUnauthorized},
+		{"profile without token", "/profile", nil, http.StatusUnauthorized},
+		{"profile with user token", "/profile", bearer(user), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject string
+			handler := Middleware(tokens)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				subject, _ = SubjectFromContext(r.Context())
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, tt.path, nil).WithContext(tenantContext("acme"))
+			for k, v := range tt.header {
+				r.Header[k] = v
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && tt.header != nil && subject == "" {
+				t.Error("subject not attached to the context")
+			}
+		})
+	}
+}
+
+func TestIssueEmbedsRoleClaims(t *testing.T) {
+	tokens := newTokens(t, time.Minute)
+	claims, err := tokens.Verify(tenantContext("acme"), issue(t, tokens, "acme", "+15550199"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "+15550199" || claims.Tenant != "acme" {
+		t.Errorf("subject %q tenant %q", claims.Subject, claims.Tenant)
+	}
+	if len(claims.Roles) != 1 || claims.Roles[0] != "admin" {
+		t.Errorf("roles = %v", claims.Roles)
+	}
+	if !claims.HasPermission(domain.PermissionUsersRead) || claims.HasPermission(domain.PermissionRolesManage) {
+		t.Errorf("permissions = %v", claims.Permissions)
+	}
+}
+
+func TestVerifyRejectsRevokedTokens(t *testing.T) {
+	source := newUserSource()
+	tokens, err := NewTokens([]byte("0123456789abcdef0123456789abcdef"), time.Minute, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := tenantContext("acme")
+
+	admin := issue(t, tokens, "acme", "+15550199")
+	if err := source.users["+15550199"].Suspend("fraud", "+15550101"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Verify(ctx, admin); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("token of a suspended user: err = %v, want %v", err, ErrUnauthenticated)
+	}
+	if err := source.users["+15550199"].Reinstate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Verify(ctx, admin); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("token issued before the suspension: err = %v, want %v", err, ErrUnauthenticated)
+	}
+
+	user := issue(t, tokens, "acme", "+15550100")
+	revokedAt := time.Now().Add(-time.Hour)
+	source.users["+15550100"].TokensValidAfter = &revokedAt
+	if _, err := tokens.Verify(ctx, user); err != nil {
+		t.Errorf("token issued after the revocation: %v", err)
+	}
+	source.users["+15550100"].RevokeTokens()
+	if _, err := tokens.Verify(ctx, user); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("revoked token: err = %v, want %v", err, ErrUnauthenticated)
+	}
+
+	delete(source.users, "+15550100")
+	if _, err := tokens.Verify(ctx, issue(t, tokens, "acme", "+15550100")); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("token of an unknown user: err = %v, want %v", err, ErrUnauthenticated)
+	}
+}
+
+func TestResolveUser(t *testing.T) {
+	caller := &Claims{Permissions: []domain.Permission{domain.PermissionUsersRead}}
+	caller.Subject = "+15550199"
+	user := &Claims{}
+	user.Subject = "+15550100"
+
+	tests := []struct {
+		name        string
+		claims      *Claims
+		phoneNumber string
+		want        string
+		err         error
+	}{
+		{name: "own by default", claims: user, want: "+15550100"},
+		{name: "own", claims: user, phoneNumber: "+15550100", want: "+15550100"},
+		{name: "another without permission", claims: user, phoneNumber: "+15550199", err: domain.ErrPermissionDenied},
+		{name: "another with permission", claims: caller, phoneNumber: "+15550100", want: "+15550100"},
+		{name: "no caller", phoneNumber: "+15550100", err: ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = WithClaims(ctx, tt.claims)
+			}
+			got, err := ResolveUser(ctx, tt.phoneNumber, domain.PermissionUsersRead)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("got %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestNewTokensRejectsShortKeys(t *testing.T) {
+	if _, err := NewTokens([]byte("short"), time.Minute, users); err == nil {
+		t.Error("short key accepted")
+	}
+}
+
+func bearer(token string) http.Header {
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
@@ -0,0 +1,137 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"midaslabs/microservices/auth/internal/domain"
+)
+
+// issuer names the auth service in the tokens it issues.
+const issuer = "midaslabs-auth"
+
+// Claims are the claims of an access token. The subject is the caller's phone
+// number. Roles and permissions are those held when the token was issued;
+// changes take effect with the next token, or once the user's tokens are
+// revoked.
+type Claims struct {
+	jwt.RegisteredClaims
+	Tenant      string              `json:"tenant"`
+	Roles       []string            `json:"roles,omitempty"`
+	Permissions []domain.Permission `json:"permissions,omitempty"`
+}
+
+// HasPermission reports whether the token grants the permission.
+func (c *Claims) HasPermission(permission domain.Permission) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// UserSource looks up a user of the tenant in ctx and the roles it holds.
+type UserSource interface {
+	GetUser(ctx context.Context, phoneNumber string) (*domain.User, error)
+	GetUserRoles(ctx context.Context, phoneNumber string) ([]*domain.Role, error)
+}
+
+// Tokens issues and verifies access tokens signed with HMAC-SHA256.
+type Tokens struct {
+	key   []byte
+	ttl   time.Duration
+	users UserSource
+}
+
+// NewTokens returns Tokens signing with key, which must be at least 32
+// bytes, and issuing tokens valid for ttl.
+func NewTokens(key []byte, ttl time.Duration, users UserSource) (*Tokens, error) {
+	if len(key) < 32 {
+		return nil, errors.New("authz: token signing key must be at least 32 bytes")
+	}
+	return &Tokens{key: key, ttl: ttl, users: users}, nil
+}
+
+// Issue returns an access token for a user of the tenant in ctx, with the
+// claims of the roles the user holds, and its expiry.
+func (t *Tokens) Issue(ctx context.Context, phoneNumber string) (string, time.Time, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	roles, err := t.users.GetUserRoles(ctx, phoneNumber)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(t.ttl)
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   phoneNumber,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Tenant: tenant.ID,
+	}
+	for _, role := range roles {
+		claims.Roles = append(claims.Roles, role.Name)
+		for _, p := range role.Permissions {
+			if !claims.HasPermission(p) {
+				claims.Permissions = append(claims.Permissions, p)
+			}
+		}
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// Verify checks the token's signature and expiry, that it was issued for the
+// tenant in ctx, and that its subject is neither suspended nor has had its
+// tokens revoked since. It returns ErrUnauthenticated otherwise.
+func (t *Tokens) Verify(ctx context.Context, token string) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return t.key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Tenant != tenant.ID {
+		return nil, fmt.Errorf("%w: token issued for another tenant", ErrUnauthenticated)
+	}
+	if claims.IssuedAt == nil {
+		return nil, fmt.Errorf("%w: token without issue time", ErrUnauthenticated)
+	}
+
+	user, err := t.users.GetUser(ctx, claims.Subject)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: unknown subject", ErrUnauthenticated)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.IsSuspended() {
+		return nil, fmt.Errorf("%w: subject suspended", ErrUnauthenticated)
+	}
+	if user.TokenRevoked(claims.IssuedAt.Time) {
+		return nil, fmt.Errorf("%w: token revoked", ErrUnauthenticated)
+	}
+	return &claims, nil
+}
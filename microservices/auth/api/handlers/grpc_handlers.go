@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/charmbracelet/log"
@@ -9,19 +10,24 @@ import (
 
 	authv1 "midaslabs/gen/auth/v1"
 
+	"midaslabs/microservices/auth/api/authz"
 	"midaslabs/microservices/auth/internal/application"
 	"midaslabs/microservices/auth/internal/domain"
 )
 
 type AuthServerHandlers struct {
-	authService *application.AuthService
-	logger      *log.Logger
+	authService  *application.AuthService
+	auditService *application.AuditService
+	tokens       *authz.Tokens
+	logger       *log.Logger
 }
 
-func NewAuthServerHandlers(logger *log.Logger, authService *application.AuthService) *AuthServerHandlers {
+func NewAuthServerHandlers(logger *log.Logger, authService *application.AuthService, auditService *application.AuditService, tokens *authz.Tokens) *AuthServerHandlers {
 	return &AuthServerHandlers{
-		authService: authService,
-		logger:      logger,
+		authService:  authService,
+		auditService: auditService,
+		tokens:       tokens,
+		logger:       logger,
 	}
 }
 
@@ -29,7 +35,7 @@ func (s *AuthServerHandlers) SignUpWithPhoneNumber(
 	ctx context.Context,
 	req *connect.Request[authv1.SignUpWithPhoneNumberRequest],
 ) (*connect.Response[authv1.SignUpWithPhoneNumberResponse], error) {
-	err := s.authService.SignUpWithPhoneNumber(ctx, req.Msg.Phone)
+	err := s.authService.SignUpWithPhoneNumber(ctx, req.Msg.Phone, req.Msg.InviteCode)
 	if err != nil {
 		s.logger.Errorf("SignUpWithPhoneNumber: failed to sign up for phone number %s: %v", req.Msg.Phone, err)
 		if err == domain.ErrUserAlreadyExists {
@@ -41,6 +47,38 @@ func (s *AuthServerHandlers) SignUpWithPhoneNumber(
 				},
 			}), nil
 
+		} else if err == domain.ErrCountryNotAllowed {
+			return connect.NewResponse(&authv1.SignUpWithPhoneNumberResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Country not allowed",
+					ErrorCode: "ERR_COUNTRY_NOT_ALLOWED",
+				},
+			}), nil
+		} else if err == domain.ErrInviteCodeRequired {
+			return connect.NewResponse(&authv1.SignUpWithPhoneNumberResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Invite code required",
+					ErrorCode: "ERR_INVITE_CODE_REQUIRED",
+				},
+			}), nil
+		} else if err == domain.ErrInvalidInviteCode {
+			return connect.NewResponse(&authv1.SignUpWithPhoneNumberResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Invalid invite code",
+					ErrorCode: "ERR_INVALID_INVITE_CODE",
+				},
+			}), nil
+		} else if err == domain.ErrNotAllowlisted {
+			return connect.NewResponse(&authv1.SignUpWithPhoneNumberResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Phone number not allowlisted",
+					ErrorCode: "ERR_NOT_ALLOWLISTED",
+				},
+			}), nil
 		}
 		return connect.NewResponse(&authv1.SignUpWithPhoneNumberResponse{
 			Status: &authv1.ResponseStatus{
@@ -109,6 +147,30 @@ func (s *AuthServerHandlers) LoginInitiate(
 					ErrorCode: "ERR_USER_NOT_FOUND",
 				},
 			}), nil
+		} else if err == domain.ErrUserSuspended {
+			return connect.NewResponse(&authv1.LoginInitiateResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "User suspended",
+					ErrorCode: "ERR_USER_SUSPENDED",
+				},
+			}), nil
+		} else if err == domain.ErrCountryNotAllowed {
+			return connect.NewResponse(&authv1.LoginInitiateResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Country not allowed",
+					ErrorCode: "ERR_COUNTRY_NOT_ALLOWED",
+				},
+			}), nil
+		} else if err == domain.ErrLoginDenied {
+			return connect.NewResponse(&authv1.LoginInitiateResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Login denied",
+					ErrorCode: "ERR_LOGIN_DENIED",
+				},
+			}), nil
 		}
 		return connect.NewResponse(&authv1.LoginInitiateResponse{
 			Status: &authv1.ResponseStatus{
@@ -132,7 +194,7 @@ func (s *AuthServerHandlers) ValidatePhoneNumberLogin(
 	ctx context.Context,
 	req *connect.Request[authv1.ValidatePhoneNumberLoginRequest],
 ) (*connect.Response[authv1.ValidatePhoneNumberLoginResponse], error) {
-	err := s.authService.ValidatePhoneNumberLogin(ctx, req.Msg.Phone, req.Msg.Otp)
+	err := s.authService.ValidatePhoneNumberLogin(ctx, req.Msg.Phone, req.Msg.Otp, req.Msg.RecoveryCode)
 	if err != nil {
 		s.logger.Errorf("ValidatePhoneNumberLogin: failed to login with phone number %s: %v", req.Msg.Phone, err)
 		if err == domain.ErrInvalidOTP {
@@ -159,6 +221,54 @@ func (s *AuthServerHandlers) ValidatePhoneNumberLogin(
 					ErrorCode: "ERR_USER_NOT_FOUND",
 				},
 			}), nil
+		} else if err == domain.ErrUserSuspended {
+			return connect.NewResponse(&authv1.ValidatePhoneNumberLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "User suspended",
+					ErrorCode: "ERR_USER_SUSPENDED",
+				},
+			}), nil
+		} else if err == domain.ErrReverificationRequired {
+			return connect.NewResponse(&authv1.ValidatePhoneNumberLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Recovery code required",
+					ErrorCode: "ERR_REVERIFICATION_REQUIRED",
+				},
+			}), nil
+		} else if err == domain.ErrReverificationPending {
+			return connect.NewResponse(&authv1.ValidatePhoneNumberLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Reverification pending",
+					ErrorCode: "ERR_REVERIFICATION_PENDING",
+				},
+			}), nil
+		} else if err == domain.ErrStepUpRequired {
+			return connect.NewResponse(&authv1.ValidatePhoneNumberLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Step-up verification required",
+					ErrorCode: "ERR_STEP_UP_REQUIRED",
+				},
+			}), nil
+		} else if err == domain.ErrLoginDenied {
+			return connect.NewResponse(&authv1.ValidatePhoneNumberLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Login denied",
+					ErrorCode: "ERR_LOGIN_DENIED",
+				},
+			}), nil
+		} else if err == domain.ErrInvalidRecoveryCode {
+			return connect.NewResponse(&authv1.ValidatePhoneNumberLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Invalid recovery code",
+					ErrorCode: "ERR_INVALID_RECOVERY_CODE",
+				},
+			}), nil
 		}
 		return connect.NewResponse(&authv1.ValidatePhoneNumberLoginResponse{
 			Status: &authv1.ResponseStatus{
@@ -169,21 +279,48 @@ func (s *AuthServerHandlers) ValidatePhoneNumberLogin(
 		}), nil
 	}
 
+	token, expiresAt, err := s.tokens.Issue(ctx, req.Msg.Phone)
+	if err != nil {
+		s.logger.Errorf("ValidatePhoneNumberLogin: failed to issue token for phone number %s: %v", req.Msg.Phone, err)
+		return connect.NewResponse(&authv1.ValidatePhoneNumberLoginResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to login",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
 	s.logger.Infof("ValidatePhoneNumberLogin: user %s logged in successfully", req.Msg.Phone)
 	return connect.NewResponse(&authv1.ValidatePhoneNumberLoginResponse{
 		Status: &authv1.ResponseStatus{
 			Success: true,
 			Message: "Login successful",
 		},
+		AccessToken: token,
+		ExpiresAt:   timestamppb.New(expiresAt),
 	}), nil
 }
 func (s *AuthServerHandlers) GetProfile(
 	ctx context.Context,
 	req *connect.Request[authv1.GetProfileRequest],
 ) (*connect.Response[authv1.GetProfileResponse], error) {
-	user, err := s.authService.GetProfile(ctx, req.Msg.Phone)
+	// Reading another user's profile needs the users:read permission
+	phoneNumber, err := authz.ResolveUser(ctx, req.Msg.Phone, domain.PermissionUsersRead)
+	if err != nil {
+		s.logger.Errorf("GetProfile: profile of phone number %s refused: %v", req.Msg.Phone, err)
+		return connect.NewResponse(&authv1.GetProfileResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Permission denied",
+				ErrorCode: "ERR_PERMISSION_DENIED",
+			},
+		}), nil
+	}
+
+	user, err := s.authService.GetProfile(ctx, phoneNumber)
 	if err != nil {
-		s.logger.Errorf("GetProfile: failed to get profile for phone number %s: %v", req.Msg.Phone, err)
+		s.logger.Errorf("GetProfile: failed to get profile for phone number %s: %v", phoneNumber, err)
 		return connect.NewResponse(&authv1.GetProfileResponse{
 			Status: &authv1.ResponseStatus{
 				Success:   false,
@@ -198,9 +335,10 @@ func (s *AuthServerHandlers) GetProfile(
 		Verified:    user.Verified,
 		CreatedAt:   &timestamppb.Timestamp{Seconds: user.CreatedAt.Unix()},
 		UpdatedAt:   &timestamppb.Timestamp{Seconds: user.UpdatedAt.Unix()},
+		Status:      string(user.Status),
 	}
 
-	s.logger.Infof("GetProfile: retrieved profile for phone number %s", req.Msg.Phone)
+	s.logger.Infof("GetProfile: retrieved profile for phone number %s", phoneNumber)
 	return connect.NewResponse(&authv1.GetProfileResponse{
 		Status: &authv1.ResponseStatus{
 			Success: true,
@@ -209,3 +347,716 @@ func (s *AuthServerHandlers) GetProfile(
 		ProfileData: profileData,
 	}), nil
 }
+
+func (s *AuthServerHandlers) GenerateRecoveryCodes(
+	ctx context.Context,
+	req *connect.Request[authv1.GenerateRecoveryCodesRequest],
+) (*connect.Response[authv1.GenerateRecoveryCodesResponse], error) {
+	phoneNumber, _ := authz.SubjectFromContext(ctx)
+	codes, err := s.authService.GenerateRecoveryCodes(ctx, phoneNumber)
+	if err != nil {
+		s.logger.Errorf("GenerateRecoveryCodes: failed to generate recovery codes for phone number %s: %v", phoneNumber, err)
+		if err == domain.ErrUserNotVerified {
+			return connect.NewResponse(&authv1.GenerateRecoveryCodesResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "User not verified",
+					ErrorCode: "ERR_USER_NOT_VERIFIED",
+				},
+			}), nil
+		}
+		return connect.NewResponse(&authv1.GenerateRecoveryCodesResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to generate recovery codes",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("GenerateRecoveryCodes: generated recovery codes for phone number %s", phoneNumber)
+	return connect.NewResponse(&authv1.GenerateRecoveryCodesResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Recovery codes generated",
+		},
+		RecoveryCodes: codes,
+	}), nil
+}
+
+func (s *AuthServerHandlers) ConfirmLogin(
+	ctx context.Context,
+	req *connect.Request[authv1.ConfirmLoginRequest],
+) (*connect.Response[authv1.ConfirmLoginResponse], error) {
+	phoneNumber, _ := authz.SubjectFromContext(ctx)
+	err := s.authService.ConfirmLogin(ctx, phoneNumber, req.Msg.AlertId)
+	if err != nil {
+		s.logger.Errorf("ConfirmLogin: failed to confirm login alert %s for phone number %s: %v", req.Msg.AlertId, phoneNumber, err)
+		if err == domain.ErrLoginAlertNotFound {
+			return connect.NewResponse(&authv1.ConfirmLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Login alert not found",
+					ErrorCode: "ERR_LOGIN_ALERT_NOT_FOUND",
+				},
+			}), nil
+		} else if err == domain.ErrLoginAlertResolved {
+			return connect.NewResponse(&authv1.ConfirmLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Login alert already resolved",
+					ErrorCode: "ERR_LOGIN_ALERT_RESOLVED",
+				},
+			}), nil
+		}
+		return connect.NewResponse(&authv1.ConfirmLoginResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to confirm login",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("ConfirmLogin: login alert %s confirmed by phone number %s", req.Msg.AlertId, phoneNumber)
+	return connect.NewResponse(&authv1.ConfirmLoginResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Login confirmed",
+		},
+	}), nil
+}
+
+func (s *AuthServerHandlers) RejectLogin(
+	ctx context.Context,
+	req *connect.Request[authv1.RejectLoginRequest],
+) (*connect.Response[authv1.RejectLoginResponse], error) {
+	phoneNumber, _ := authz.SubjectFromContext(ctx)
+	err := s.authService.RejectLogin(ctx, phoneNumber, req.Msg.AlertId)
+	if err != nil {
+		s.logger.Errorf("RejectLogin: failed to reject login alert %s for phone number %s: %v", req.Msg.AlertId, phoneNumber, err)
+		if err == domain.ErrLoginAlertNotFound {
+			return connect.NewResponse(&authv1.RejectLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Login alert not found",
+					ErrorCode: "ERR_LOGIN_ALERT_NOT_FOUND",
+				},
+			}), nil
+		} else if err == domain.ErrLoginAlertResolved {
+			return connect.NewResponse(&authv1.RejectLoginResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Login alert already resolved",
+					ErrorCode: "ERR_LOGIN_ALERT_RESOLVED",
+				},
+			}), nil
+		}
+		return connect.NewResponse(&authv1.RejectLoginResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to reject login",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("RejectLogin: login alert %s rejected by phone number %s", req.Msg.AlertId, phoneNumber)
+	return connect.NewResponse(&authv1.RejectLoginResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Login rejected",
+		},
+	}), nil
+}
+
+func (s *AuthServerHandlers) ListActivities(
+	ctx context.Context,
+	req *connect.Request[authv1.ListActivitiesRequest],
+) (*connect.Response[authv1.ListActivitiesResponse], error) {
+	filter := domain.ActivityFilter{
+		Outcome: domain.ActivityOutcome(req.Msg.Outcome),
+		Limit:   int(req.Msg.PageSize),
+	}
+	for _, t := range req.Msg.Types {
+		filter.Types = append(filter.Types, domain.ActivityType(t))
+	}
+	if req.Msg.Since != nil {
+		filter.Since = req.Msg.Since.AsTime()
+	}
+	if req.Msg.Until != nil {
+		filter.Until = req.Msg.Until.AsTime()
+	}
+
+	// Listing another user's activity needs the activities:read permission
+	var page *domain.ActivityPage
+	var err error
+	filter.PhoneNumber, err = authz.ResolveUser(ctx, req.Msg.Phone, domain.PermissionActivitiesRead)
+	if err == nil && req.Msg.Cursor != "" {
+		filter.After, err = domain.DecodeActivityCursor(req.Msg.Cursor)
+	}
+	if err == nil {
+		page, err = s.authService.ListActivities(ctx, filter)
+	}
+	if err != nil {
+		s.logger.Errorf("ListActivities: failed to list activities for phone number %s: %v", req.Msg.Phone, err)
+		if err == domain.ErrPermissionDenied {
+			return connect.NewResponse(&authv1.ListActivitiesResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Permission denied",
+					ErrorCode: "ERR_PERMISSION_DENIED",
+				},
+			}), nil
+		} else if err == domain.ErrInvalidCursor {
+			return connect.NewResponse(&authv1.ListActivitiesResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Invalid cursor",
+					ErrorCode: "ERR_INVALID_CURSOR",
+				},
+			}), nil
+		}
+		return connect.NewResponse(&authv1.ListActivitiesResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to list activities",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	activities := make([]*authv1.ActivityData, 0, len(page.Activities))
+	for _, activity := range page.Activities {
+		activities = append(activities, activityData(activity))
+	}
+
+	s.logger.Infof("ListActivities: retrieved %d activities", len(activities))
+	return connect.NewResponse(&authv1.ListActivitiesResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Activities retrieved successfully",
+		},
+		Activities: activities,
+		NextCursor: page.NextCursor,
+	}), nil
+}
+
+func (s *AuthServerHandlers) SuspendUser(
+	ctx context.Context,
+	req *connect.Request[authv1.SuspendUserRequest],
+) (*connect.Response[authv1.SuspendUserResponse], error) {
+	actor := actorFromContext(ctx, req.Msg.Actor)
+	err := s.authService.SuspendUser(ctx, req.Msg.Phone, req.Msg.Reason, actor)
+	if err != nil {
+		s.logger.Errorf("SuspendUser: failed to suspend phone number %s: %v", req.Msg.Phone, err)
+		if err == domain.ErrUserNotFound {
+			return connect.NewResponse(&authv1.SuspendUserResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "User not found",
+					ErrorCode: "ERR_USER_NOT_FOUND",
+				},
+			}), nil
+		} else if err == domain.ErrUserSuspended {
+			return connect.NewResponse(&authv1.SuspendUserResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "User already suspended",
+					ErrorCode: "ERR_USER_SUSPENDED",
+				},
+			}), nil
+		}
+		return connect.NewResponse(&authv1.SuspendUserResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to suspend user",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("SuspendUser: phone number %s suspended by %s", req.Msg.Phone, actor)
+	return connect.NewResponse(&authv1.SuspendUserResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "User suspended",
+		},
+	}), nil
+}
+
+func (s *AuthServerHandlers) ReinstateUser(
+	ctx context.Context,
+	req *connect.Request[authv1.ReinstateUserRequest],
+) (*connect.Response[authv1.ReinstateUserResponse], error) {
+	actor := actorFromContext(ctx, req.Msg.Actor)
+	err := s.authService.ReinstateUser(ctx, req.Msg.Phone, actor)
+	if err != nil {
+		s.logger.Errorf("ReinstateUser: failed to reinstate phone number %s: %v", req.Msg.Phone, err)
+		if err == domain.ErrUserNotFound {
+			return connect.NewResponse(&authv1.ReinstateUserResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "User not found",
+					ErrorCode: "ERR_USER_NOT_FOUND",
+				},
+			}), nil
+		} else if err == domain.ErrUserNotSuspended {
+			return connect.NewResponse(&authv1.ReinstateUserResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "User not suspended",
+					ErrorCode: "ERR_USER_NOT_SUSPENDED",
+				},
+			}), nil
+		}
+		return connect.NewResponse(&authv1.ReinstateUserResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to reinstate user",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("ReinstateUser: phone number %s reinstated by %s", req.Msg.Phone, actor)
+	return connect.NewResponse(&authv1.ReinstateUserResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "User reinstated",
+		},
+	}), nil
+}
+
+func (s *AuthServerHandlers) ListSuspendedUsers(
+	ctx context.Context,
+	req *connect.Request[authv1.ListSuspendedUsersRequest],
+) (*connect.Response[authv1.ListSuspendedUsersResponse], error) {
+	users, err := s.authService.ListSuspendedUsers(ctx)
+	if err != nil {
+		s.logger.Errorf("ListSuspendedUsers: failed to list suspended users: %v", err)
+		return connect.NewResponse(&authv1.ListSuspendedUsersResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to list suspended users",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	suspended := make([]*authv1.SuspendedUser, 0, len(users))
+	for _, user := range users {
+		entry := &authv1.SuspendedUser{PhoneNumber: user.PhoneNumber}
+		if user.Suspension != nil {
+			entry.Reason = user.Suspension.Reason
+			entry.SuspendedBy = user.Suspension.SuspendedBy
+			entry.SuspendedAt = &timestamppb.Timestamp{Seconds: user.Suspension.SuspendedAt.Unix()}
+		}
+		suspended = append(suspended, entry)
+	}
+
+	s.logger.Infof("ListSuspendedUsers: retrieved %d suspended users", len(suspended))
+	return connect.NewResponse(&authv1.ListSuspendedUsersResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Suspended users retrieved successfully",
+		},
+		Users: suspended,
+	}), nil
+}
+
+func (s *AuthServerHandlers) AssignRole(
+	ctx context.Context,
+	req *connect.Request[authv1.AssignRoleRequest],
+) (*connect.Response[authv1.AssignRoleResponse], error) {
+	actor := actorFromContext(ctx, "")
+	err := s.authService.AssignRole(ctx, req.Msg.Phone, req.Msg.Role, actor)
+	if err != nil {
+		s.logger.Errorf("AssignRole: failed to assign role %s to phone number %s: %v", req.Msg.Role, req.Msg.Phone, err)
+		if err == domain.ErrUserNotFound {
+			return connect.NewResponse(&authv1.AssignRoleResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "User not found",
+					ErrorCode: "ERR_USER_NOT_FOUND",
+				},
+			}), nil
+		} else if err == domain.ErrRoleNotFound {
+			return connect.NewResponse(&authv1.AssignRoleResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Role not found",
+					ErrorCode: "ERR_ROLE_NOT_FOUND",
+				},
+			}), nil
+		}
+		return connect.NewResponse(&authv1.AssignRoleResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to assign role",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("AssignRole: role %s assigned to phone number %s by %s", req.Msg.Role, req.Msg.Phone, actor)
+	return connect.NewResponse(&authv1.AssignRoleResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Role assigned",
+		},
+	}), nil
+}
+
+func (s *AuthServerHandlers) RevokeRole(
+	ctx context.Context,
+	req *connect.Request[authv1.RevokeRoleRequest],
+) (*connect.Response[authv1.RevokeRoleResponse], error) {
+	actor := actorFromContext(ctx, "")
+	err := s.authService.RevokeRole(ctx, req.Msg.Phone, req.Msg.Role, actor)
+	if err != nil {
+		s.logger.Errorf("RevokeRole: failed to revoke role %s from phone number %s: %v", req.Msg.Role, req.Msg.Phone, err)
+		if err == domain.ErrRoleNotAssigned {
+			return connect.NewResponse(&authv1.RevokeRoleResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Role not assigned",
+					ErrorCode: "ERR_ROLE_NOT_ASSIGNED",
+				},
+			}), nil
+		}
+		return connect.NewResponse(&authv1.RevokeRoleResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to revoke role",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("RevokeRole: role %s revoked from phone number %s by %s", req.Msg.Role, req.Msg.Phone, actor)
+	return connect.NewResponse(&authv1.RevokeRoleResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Role revoked",
+		},
+	}), nil
+}
+
+func (s *AuthServerHandlers) ListUserRoles(
+	ctx context.Context,
+	req *connect.Request[authv1.ListUserRolesRequest],
+) (*connect.Response[authv1.ListUserRolesResponse], error) {
+	roles, err := s.authService.GetUserRoles(ctx, req.Msg.Phone)
+	if err != nil {
+		s.logger.Errorf("ListUserRoles: failed to list roles for phone number %s: %v", req.Msg.Phone, err)
+		return connect.NewResponse(&authv1.ListUserRolesResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to list roles",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	roleData := make([]*authv1.RoleData, 0, len(roles))
+	for _, role := range roles {
+		permissions := make([]string, 0, len(role.Permissions))
+		for _, p := range role.Permissions {
+			permissions = append(permissions, string(p))
+		}
+		roleData = append(roleData, &authv1.RoleData{Name: role.Name, Permissions: permissions})
+	}
+
+	s.logger.Infof("ListUserRoles: retrieved roles for phone number %s", req.Msg.Phone)
+	return connect.NewResponse(&authv1.ListUserRolesResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Roles retrieved successfully",
+		},
+		Roles: roleData,
+	}), nil
+}
+
+func (s *AuthServerHandlers) CreateInviteCode(
+	ctx context.Context,
+	req *connect.Request[authv1.CreateInviteCodeRequest],
+) (*connect.Response[authv1.CreateInviteCodeResponse], error) {
+	actor := actorFromContext(ctx, "")
+	validity := time.Duration(req.Msg.ValidForSeconds) * time.Second
+	invite, err := s.authService.CreateInviteCode(ctx, int(req.Msg.MaxUses), validity, actor)
+	if err != nil {
+		s.logger.Errorf("CreateInviteCode: failed to create invite code: %v", err)
+		return connect.NewResponse(&authv1.CreateInviteCodeResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to create invite code",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("CreateInviteCode: invite code for %d uses created by %s", invite.MaxUses, actor)
+	return connect.NewResponse(&authv1.CreateInviteCodeResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Invite code created",
+		},
+		InviteCode: inviteCodeData(invite),
+	}), nil
+}
+
+func (s *AuthServerHandlers) ListInviteCodes(
+	ctx context.Context,
+	req *connect.Request[authv1.ListInviteCodesRequest],
+) (*connect.Response[authv1.ListInviteCodesResponse], error) {
+	invites, err := s.authService.ListInviteCodes(ctx)
+	if err != nil {
+		s.logger.Errorf("ListInviteCodes: failed to list invite codes: %v", err)
+		return connect.NewResponse(&authv1.ListInviteCodesResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to list invite codes",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	data := make([]*authv1.InviteCodeData, 0, len(invites))
+	for _, invite := range invites {
+		data = append(data, inviteCodeData(invite))
+	}
+
+	s.logger.Infof("ListInviteCodes: retrieved %d invite codes", len(data))
+	return connect.NewResponse(&authv1.ListInviteCodesResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Invite codes retrieved successfully",
+		},
+		InviteCodes: data,
+	}), nil
+}
+
+func (s *AuthServerHandlers) ExportActivities(
+	ctx context.Context,
+	req *connect.Request[authv1.ExportActivitiesRequest],
+	stream *connect.ServerStream[authv1.ExportActivitiesResponse],
+) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return connect.NewError(connect.CodeInternal, err)
+	}
+
+	opts := application.ExportOptions{
+		TenantID:     tenant.ID,
+		Pseudonymize: req.Msg.Pseudonymize,
+	}
+	if req.Msg.Since != nil {
+		opts.Filter.Since = req.Msg.Since.AsTime()
+	}
+	if req.Msg.Until != nil {
+		opts.Filter.Until = req.Msg.Until.AsTime()
+	}
+	if req.Msg.Cursor != "" {
+		if opts.Filter.AfterID, err = domain.ParseExportCursor(req.Msg.Cursor); err != nil {
+			return connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	}
+
+	lastID, err := s.auditService.ExportActivities(ctx, opts, func(activity *domain.Activity) error {
+		return stream.Send(&authv1.ExportActivitiesResponse{
+			Activity: activityData(activity),
+			Cursor:   domain.ExportCursor(activity.ID),
+		})
+	})
+	if err != nil {
+		s.logger.Errorf("ExportActivities: export stopped after activity %d: %v", lastID, err)
+		if err == domain.ErrPseudonymKeyNotConfigured {
+			return connect.NewError(connect.CodeFailedPrecondition, err)
+		}
+		return connect.NewError(connect.CodeInternal, err)
+	}
+
+	s.logger.Infof("ExportActivities: exported activities up to %d", lastID)
+	return nil
+}
+
+func (s *AuthServerHandlers) GetOTPTimeline(
+	ctx context.Context,
+	req *connect.Request[authv1.GetOTPTimelineRequest],
+) (*connect.Response[authv1.GetOTPTimelineResponse], error) {
+	var since time.Time
+	if req.Msg.Since != nil {
+		since = req.Msg.Since.AsTime()
+	}
+
+	events, err := s.authService.GetOTPTimeline(ctx, req.Msg.Phone, since)
+	if err != nil {
+		s.logger.Errorf("GetOTPTimeline: failed to get OTP timeline for phone number %s: %v", req.Msg.Phone, err)
+		return connect.NewResponse(&authv1.GetOTPTimelineResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to get OTP timeline",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	data := make([]*authv1.OTPEventData, 0, len(events))
+	for _, event := range events {
+		data = append(data, &authv1.OTPEventData{
+			OtpId:     event.OTPID,
+			Type:      string(event.Type),
+			Detail:    event.Detail,
+			Source:    event.Source,
+			Timestamp: &timestamppb.Timestamp{Seconds: event.Timestamp.Unix()},
+		})
+	}
+
+	s.logger.Infof("GetOTPTimeline: retrieved %d OTP events", len(data))
+	return connect.NewResponse(&authv1.GetOTPTimelineResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "OTP timeline retrieved successfully",
+		},
+		Events: data,
+	}), nil
+}
+
+func activityData(activity *domain.Activity) *authv1.ActivityData {
+	data := &authv1.ActivityData{
+		PhoneNumber:   activity.PhoneNumber,
+		Type:          string(activity.Type),
+		Actor:         activity.Actor,
+		Metadata:      activity.Metadata,
+		Ip:            activity.Client.IP,
+		UserAgent:     activity.Client.UserAgent,
+		DeviceId:      activity.Client.DeviceID,
+		RequestId:     activity.Client.RequestID,
+		Outcome:       string(activity.Outcome),
+		FailureReason: activity.FailureReason,
+		Timestamp:     &timestamppb.Timestamp{Seconds: activity.Timestamp.Unix()},
+	}
+	if risk := activity.Risk; risk != nil {
+		data.RiskScore = int32(risk.Score)
+		data.RiskDecision = string(risk.Decision)
+		for _, signal := range risk.Signals {
+			data.RiskSignals = append(data.RiskSignals, &authv1.RiskSignalData{
+				Name:   signal.Name,
+				Score:  int32(signal.Score),
+				Detail: signal.Detail,
+			})
+		}
+	}
+	return data
+}
+
+func inviteCodeData(invite *domain.InviteCode) *authv1.InviteCodeData {
+	data := &authv1.InviteCodeData{
+		Code:      invite.Code,
+		MaxUses:   int32(invite.MaxUses),
+		Uses:      int32(invite.Uses),
+		CreatedBy: invite.CreatedBy,
+		CreatedAt: &timestamppb.Timestamp{Seconds: invite.CreatedAt.Unix()},
+	}
+	if invite.ExpiresAt != nil {
+		data.ExpiresAt = &timestamppb.Timestamp{Seconds: invite.ExpiresAt.Unix()}
+	}
+	return data
+}
+
+func (s *AuthServerHandlers) AddAllowlistEntry(
+	ctx context.Context,
+	req *connect.Request[authv1.AddAllowlistEntryRequest],
+) (*connect.Response[authv1.AddAllowlistEntryResponse], error) {
+	actor := actorFromContext(ctx, "")
+	err := s.authService.AddAllowlistEntry(ctx, req.Msg.Entry, req.Msg.IsPrefix, actor)
+	if err != nil {
+		s.logger.Errorf("AddAllowlistEntry: failed to allowlist %s: %v", req.Msg.Entry, err)
+		return connect.NewResponse(&authv1.AddAllowlistEntryResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to add allowlist entry",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("AddAllowlistEntry: %s allowlisted by %s", req.Msg.Entry, actor)
+	return connect.NewResponse(&authv1.AddAllowlistEntryResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Allowlist entry added",
+		},
+	}), nil
+}
+
+func (s *AuthServerHandlers) RemoveAllowlistEntry(
+	ctx context.Context,
+	req *connect.Request[authv1.RemoveAllowlistEntryRequest],
+) (*connect.Response[authv1.RemoveAllowlistEntryResponse], error) {
+	err := s.authService.RemoveAllowlistEntry(ctx, req.Msg.Entry)
+	if err != nil {
+		s.logger.Errorf("RemoveAllowlistEntry: failed to remove %s: %v", req.Msg.Entry, err)
+		if err == domain.ErrAllowlistEntryNotFound {
+			return connect.NewResponse(&authv1.RemoveAllowlistEntryResponse{
+				Status: &authv1.ResponseStatus{
+					Success:   false,
+					Message:   "Allowlist entry not found",
+					ErrorCode: "ERR_ALLOWLIST_ENTRY_NOT_FOUND",
+				},
+			}), nil
+		}
+		return connect.NewResponse(&authv1.RemoveAllowlistEntryResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to remove allowlist entry",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	s.logger.Infof("RemoveAllowlistEntry: %s removed from allowlist", req.Msg.Entry)
+	return connect.NewResponse(&authv1.RemoveAllowlistEntryResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Allowlist entry removed",
+		},
+	}), nil
+}
+
+func (s *AuthServerHandlers) ListAllowlistEntries(
+	ctx context.Context,
+	req *connect.Request[authv1.ListAllowlistEntriesRequest],
+) (*connect.Response[authv1.ListAllowlistEntriesResponse], error) {
+	entries, err := s.authService.ListAllowlistEntries(ctx)
+	if err != nil {
+		s.logger.Errorf("ListAllowlistEntries: failed to list allowlist: %v", err)
+		return connect.NewResponse(&authv1.ListAllowlistEntriesResponse{
+			Status: &authv1.ResponseStatus{
+				Success:   false,
+				Message:   "Failed to list allowlist entries",
+				ErrorCode: "ERR_INTERNAL",
+			},
+		}), nil
+	}
+
+	data := make([]*authv1.AllowlistEntryData, 0, len(entries))
+	for _, entry := range entries {
+		data = append(data, &authv1.AllowlistEntryData{
+			Entry:     entry.Entry,
+			IsPrefix:  entry.IsPrefix,
+			CreatedBy: entry.CreatedBy,
+			CreatedAt: &timestamppb.Timestamp{Seconds: entry.CreatedAt.Unix()},
+		})
+	}
+
+	s.logger.Infof("ListAllowlistEntries: retrieved %d allowlist entries", len(data))
+	return connect.NewResponse(&authv1.ListAllowlistEntriesResponse{
+		Status: &authv1.ResponseStatus{
+			Success: true,
+			Message: "Allowlist entries retrieved successfully",
+		},
+		Entries: data,
+	}), nil
+}
@@ -3,28 +3,43 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"midaslabs/microservices/auth/api/authz"
 	"midaslabs/microservices/auth/internal/application"
 	"midaslabs/microservices/auth/internal/domain"
 	"net/http"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
 
 type AuthHandler struct {
-	authService *application.AuthService
-	logger      *log.Logger
+	authService  *application.AuthService
+	auditService *application.AuditService
+	tokens       *authz.Tokens
+	logger       *log.Logger
 }
 
-func NewAuthHandler(logger *log.Logger, authService *application.AuthService) *AuthHandler {
+func NewAuthHandler(logger *log.Logger, authService *application.AuthService, auditService *application.AuditService, tokens *authz.Tokens) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		logger:      logger,
+		authService:  authService,
+		auditService: auditService,
+		tokens:       tokens,
+		logger:       logger,
 	}
 }
 
+// actorFromContext prefers the authenticated caller over the actor named in the request.
+func actorFromContext(ctx context.Context, requested string) string {
+	if subject, ok := authz.SubjectFromContext(ctx); ok {
+		return subject
+	}
+	return requested
+}
+
 func (h *AuthHandler) SignUpWithPhoneNumber(w http.ResponseWriter, r *http.Request) {
 	var request struct {
 		PhoneNumber string `json:"phone"`
+		InviteCode  string `json:"invite_code"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		h.logger.Errorf("Handler: SignUpWithPhoneNumber: failed to decode request: %v", err)
@@ -32,10 +47,18 @@ func (h *AuthHandler) SignUpWithPhoneNumber(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := h.authService.SignUpWithPhoneNumber(context.Background(), request.PhoneNumber); err != nil {
+	if err := h.authService.SignUpWithPhoneNumber(r.Context(), request.PhoneNumber, request.InviteCode); err != nil {
 		h.logger.Errorf("Handler: SignUpWithPhoneNumber: failed to sign up for phone number %s: %v", request.PhoneNumber, err)
 		if err == domain.ErrUserAlreadyExists {
 			http.Error(w, "User already exists", http.StatusConflict)
+		} else if err == domain.ErrCountryNotAllowed {
+			http.Error(w, "Country not allowed", http.StatusForbidden)
+		} else if err == domain.ErrInviteCodeRequired {
+			http.Error(w, "Invite code required", http.StatusForbidden)
+		} else if err == domain.ErrInvalidInviteCode {
+			http.Error(w, "Invalid invite code", http.StatusForbidden)
+		} else if err == domain.ErrNotAllowlisted {
+			http.Error(w, "Phone number not allowlisted", http.StatusForbidden)
 		} else {
 			http.Error(w, "Failed to sign up", http.StatusInternalServerError)
 		}
@@ -58,7 +81,7 @@ func (h *AuthHandler) VerifyPhoneNumber(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.authService.VerifyPhoneNumber(context.Background(), request.PhoneNumber, request.OTP); err != nil {
+	if err := h.authService.VerifyPhoneNumber(r.Context(), request.PhoneNumber, request.OTP); err != nil {
 		h.logger.Errorf("Handler: VerifyPhoneNumber: failed to verify phone number %s: %v", request.PhoneNumber, err)
 		http.Error(w, "Failed to verify phone number", http.StatusInternalServerError)
 		return
@@ -79,11 +102,17 @@ func (h *AuthHandler) LoginInitiate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.authService.LoginInitiate(context.Background(), request.PhoneNumber)
+	err := h.authService.LoginInitiate(r.Context(), request.PhoneNumber)
 	if err != nil {
 		h.logger.Errorf("Handler: LoginInitiate: failed to initiate login for phone number %s: %v", request.PhoneNumber, err)
 		if err == domain.ErrUserNotFound {
 			http.Error(w, "User not found", http.StatusNotFound)
+		} else if err == domain.ErrUserSuspended {
+			http.Error(w, "User suspended", http.StatusForbidden)
+		} else if err == domain.ErrCountryNotAllowed {
+			http.Error(w, "Country not allowed", http.StatusForbidden)
+		} else if err == domain.ErrLoginDenied {
+			http.Error(w, "Login denied", http.StatusForbidden)
 		} else {
 			http.Error(w, "Failed to initiate login", http.StatusInternalServerError)
 		}
@@ -97,8 +126,9 @@ func (h *AuthHandler) LoginInitiate(w http.ResponseWriter, r *http.Request) {
 
 func (h *AuthHandler) ValidatePhoneNumberLogin(w http.ResponseWriter, r *http.Request) {
 	var request struct {
-		PhoneNumber string `json:"phone"`
-		OTP         string `json:"otp"`
+		PhoneNumber  string `json:"phone"`
+		OTP          string `json:"otp"`
+		RecoveryCode string `json:"recovery_code"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		h.logger.Errorf("Handler: LoginWithPhoneNumberAndOTP: failed to decode request: %v", err)
@@ -106,15 +136,40 @@ func (h *AuthHandler) ValidatePhoneNumberLogin(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if err := h.authService.ValidatePhoneNumberLogin(context.Background(), request.PhoneNumber, request.OTP); err != nil {
+	if err := h.authService.ValidatePhoneNumberLogin(r.Context(), request.PhoneNumber, request.OTP, request.RecoveryCode); err != nil {
 		h.logger.Errorf("Handler: LoginWithPhoneNumberAndOTP: failed to login with phone number %s: %v", request.PhoneNumber, err)
+		if err == domain.ErrUserSuspended {
+			http.Error(w, "User suspended", http.StatusForbidden)
+		} else if err == domain.ErrReverificationRequired {
+			http.Error(w, "Recovery code required", http.StatusUnauthorized)
+		} else if err == domain.ErrReverificationPending {
+			http.Error(w, "Reverification pending", http.StatusForbidden)
+		} else if err == domain.ErrStepUpRequired {
+			http.Error(w, "Step-up verification required", http.StatusUnauthorized)
+		} else if err == domain.ErrLoginDenied {
+			http.Error(w, "Login denied", http.StatusForbidden)
+		} else if err == domain.ErrInvalidRecoveryCode {
+			http.Error(w, "Invalid recovery code", http.StatusUnauthorized)
+		} else {
+			http.Error(w, "Failed to login", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	token, expiresAt, err := h.tokens.Issue(r.Context(), request.PhoneNumber)
+	if err != nil {
+		h.logger.Errorf("Handler: LoginWithPhoneNumberAndOTP: failed to issue token for phone number %s: %v", request.PhoneNumber, err)
 		http.Error(w, "Failed to login", http.StatusInternalServerError)
 		return
 	}
 
 	h.logger.Infof("Handler: LoginWithPhoneNumberAndOTP: user %s logged in successfully", request.PhoneNumber)
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Login successful"))
+	json.NewEncoder(w).Encode(struct {
+		AccessToken string    `json:"access_token"`
+		ExpiresAt   time.Time `json:"expires_at"`
+	}{token, expiresAt})
 }
 
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
@@ -127,14 +182,531 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	profile, err := h.authService.GetProfile(context.Background(), request.PhoneNumber)
+	// Reading another user's profile needs the users:read permission
+	phoneNumber, err := authz.ResolveUser(r.Context(), request.PhoneNumber, domain.PermissionUsersRead)
 	if err != nil {
-		h.logger.Errorf("Handler: GetProfile: failed to get profile for phone number %s: %v", request.PhoneNumber, err)
+		h.logger.Errorf("Handler: GetProfile: profile of phone number %s refused: %v", request.PhoneNumber, err)
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+
+	profile, err := h.authService.GetProfile(r.Context(), phoneNumber)
+	if err != nil {
+		h.logger.Errorf("Handler: GetProfile: failed to get profile for phone number %s: %v", phoneNumber, err)
 		http.Error(w, "Failed to get profile", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Infof("Handler: GetProfile: retrieved profile for phone number %s", request.PhoneNumber)
+	h.logger.Infof("Handler: GetProfile: retrieved profile for phone number %s", phoneNumber)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct {
+		PhoneNumber string    `json:"phone"`
+		Verified    bool      `json:"verified"`
+		Status      string    `json:"status"`
+		CreatedAt   time.Time `json:"created_at"`
+		UpdatedAt   time.Time `json:"updated_at"`
+	}{profile.PhoneNumber, profile.Verified, string(profile.Status), profile.CreatedAt, profile.UpdatedAt})
+}
+
+func (h *AuthHandler) GenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	phoneNumber, _ := authz.SubjectFromContext(r.Context())
+	codes, err := h.authService.GenerateRecoveryCodes(r.Context(), phoneNumber)
+	if err != nil {
+		h.logger.Errorf("Handler: GenerateRecoveryCodes: failed to generate recovery codes for phone number %s: %v", phoneNumber, err)
+		if err == domain.ErrUserNotVerified {
+			http.Error(w, "User not verified", http.StatusForbidden)
+		} else {
+			http.Error(w, "Failed to generate recovery codes", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Infof("Handler: GenerateRecoveryCodes: generated recovery codes for phone number %s", phoneNumber)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(codes)
+}
+
+func (h *AuthHandler) ConfirmLogin(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		AlertID string `json:"alert_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: ConfirmLogin: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	phoneNumber, _ := authz.SubjectFromContext(r.Context())
+	if err := h.authService.ConfirmLogin(r.Context(), phoneNumber, request.AlertID); err != nil {
+		h.logger.Errorf("Handler: ConfirmLogin: failed to confirm login alert %s for phone number %s: %v", request.AlertID, phoneNumber, err)
+		if err == domain.ErrLoginAlertNotFound {
+			http.Error(w, "Login alert not found", http.StatusNotFound)
+		} else if err == domain.ErrLoginAlertResolved {
+			http.Error(w, "Login alert already resolved", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to confirm login", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Infof("Handler: ConfirmLogin: login alert %s confirmed by phone number %s", request.AlertID, phoneNumber)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Login confirmed"))
+}
+
+func (h *AuthHandler) RejectLogin(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		AlertID string `json:"alert_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: RejectLogin: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	phoneNumber, _ := authz.SubjectFromContext(r.Context())
+	if err := h.authService.RejectLogin(r.Context(), phoneNumber, request.AlertID); err != nil {
+		h.logger.Errorf("Handler: RejectLogin: failed to reject login alert %s for phone number %s: %v", request.AlertID, phoneNumber, err)
+		if err == domain.ErrLoginAlertNotFound {
+			http.Error(w, "Login alert not found", http.StatusNotFound)
+		} else if err == domain.ErrLoginAlertResolved {
+			http.Error(w, "Login alert already resolved", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to reject login", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Infof("Handler: RejectLogin: login alert %s rejected by phone number %s", request.AlertID, phoneNumber)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Login rejected"))
+}
+
+func (h *AuthHandler) ListActivities(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		PhoneNumber string    `json:"phone"`
+		Types       []string  `json:"types"`
+		Outcome     string    `json:"outcome"`
+		Since       time.Time `json:"since"`
+		Until       time.Time `json:"until"`
+		PageSize    int       `json:"page_size"`
+		Cursor      string    `json:"cursor"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: ListActivities: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	filter := domain.ActivityFilter{
+		Outcome: domain.ActivityOutcome(request.Outcome),
+		Since:   request.Since,
+		Until:   request.Until,
+		Limit:   request.PageSize,
+	}
+	for _, t := range request.Types {
+		filter.Types = append(filter.Types, domain.ActivityType(t))
+	}
+	if request.Cursor != "" {
+		after, err := domain.DecodeActivityCursor(request.Cursor)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		filter.After = after
+	}
+
+	// Listing another user's activity needs the activities:read permission
+	phoneNumber, err := authz.ResolveUser(r.Context(), request.PhoneNumber, domain.PermissionActivitiesRead)
+	if err != nil {
+		h.logger.Errorf("Handler: ListActivities: activities of phone number %s refused: %v", request.PhoneNumber, err)
+		http.Error(w, "Permission denied", http.StatusForbidden)
+		return
+	}
+	filter.PhoneNumber = phoneNumber
+
+	page, err := h.authService.ListActivities(r.Context(), filter)
+	if err != nil {
+		h.logger.Errorf("Handler: ListActivities: failed to list activities for phone number %s: %v", request.PhoneNumber, err)
+		if err == domain.ErrPermissionDenied {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+		} else {
+			http.Error(w, "Failed to list activities", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Infof("Handler: ListActivities: retrieved %d activities", len(page.Activities))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+func (h *AuthHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		PhoneNumber string `json:"phone"`
+		Reason      string `json:"reason"`
+		Actor       string `json:"actor"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: SuspendUser: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromContext(r.Context(), request.Actor)
+	if err := h.authService.SuspendUser(r.Context(), request.PhoneNumber, request.Reason, actor); err != nil {
+		h.logger.Errorf("Handler: SuspendUser: failed to suspend phone number %s: %v", request.PhoneNumber, err)
+		if err == domain.ErrUserNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else if err == domain.ErrUserSuspended {
+			http.Error(w, "User already suspended", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to suspend user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Infof("Handler: SuspendUser: phone number %s suspended by %s", request.PhoneNumber, actor)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("User suspended"))
+}
+
+func (h *AuthHandler) ReinstateUser(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		PhoneNumber string `json:"phone"`
+		Actor       string `json:"actor"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: ReinstateUser: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromContext(r.Context(), request.Actor)
+	if err := h.authService.ReinstateUser(r.Context(), request.PhoneNumber, actor); err != nil {
+		h.logger.Errorf("Handler: ReinstateUser: failed to reinstate phone number %s: %v", request.PhoneNumber, err)
+		if err == domain.ErrUserNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else if err == domain.ErrUserNotSuspended {
+			http.Error(w, "User not suspended", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to reinstate user", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Infof("Handler: ReinstateUser: phone number %s reinstated by %s", request.PhoneNumber, actor)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("User reinstated"))
+}
+
+func (h *AuthHandler) ListSuspendedUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.authService.ListSuspendedUsers(r.Context())
+	if err != nil {
+		h.logger.Errorf("Handler: ListSuspendedUsers: failed to list suspended users: %v", err)
+		http.Error(w, "Failed to list suspended users", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Infof("Handler: ListSuspendedUsers: retrieved %d suspended users", len(users))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+func (h *AuthHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		PhoneNumber string `json:"phone"`
+		Role        string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: AssignRole: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromContext(r.Context(), "")
+	if err := h.authService.AssignRole(r.Context(), request.PhoneNumber, request.Role, actor); err != nil {
+		h.logger.Errorf("Handler: AssignRole: failed to assign role %s to phone number %s: %v", request.Role, request.PhoneNumber, err)
+		if err == domain.ErrUserNotFound {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else if err == domain.ErrRoleNotFound {
+			http.Error(w, "Role not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to assign role", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Infof("Handler: AssignRole: role %s assigned to phone number %s by %s", request.Role, request.PhoneNumber, actor)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Role assigned"))
+}
+
+func (h *AuthHandler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		PhoneNumber string `json:"phone"`
+		Role        string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: RevokeRole: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromContext(r.Context(), "")
+	if err := h.authService.RevokeRole(r.Context(), request.PhoneNumber, request.Role, actor); err != nil {
+		h.logger.Errorf("Handler: RevokeRole: failed to revoke role %s from phone number %s: %v", request.Role, request.PhoneNumber, err)
+		if err == domain.ErrRoleNotAssigned {
+			http.Error(w, "Role not assigned", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to revoke role", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Infof("Handler: RevokeRole: role %s revoked from phone number %s by %s", request.Role, request.PhoneNumber, actor)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Role revoked"))
+}
+
+func (h *AuthHandler) ListUserRoles(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		PhoneNumber string `json:"phone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: ListUserRoles: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	roles, err := h.authService.GetUserRoles(r.Context(), request.PhoneNumber)
+	if err != nil {
+		h.logger.Errorf("Handler: ListUserRoles: failed to list roles for phone number %s: %v", request.PhoneNumber, err)
+		http.Error(w, "Failed to list roles", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Infof("Handler: ListUserRoles: retrieved roles for phone number %s", request.PhoneNumber)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(roles)
+}
+
+func (h *AuthHandler) CreateInviteCode(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		MaxUses         int   `json:"max_uses"`
+		ValidForSeconds int64 `json:"valid_for_seconds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: CreateInviteCode: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromContext(r.Context(), "")
+	validity := time.Duration(request.ValidForSeconds) * time.Second
+	invite, err := h.authService.CreateInviteCode(r.Context(), request.MaxUses, validity, actor)
+	if err != nil {
+		h.logger.Errorf("Handler: CreateInviteCode: failed to create invite code: %v", err)
+		http.Error(w, "Failed to create invite code", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Infof("Handler: CreateInviteCode: invite code for %d uses created by %s", invite.MaxUses, actor)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(invite)
+}
+
+func (h *AuthHandler) ListInviteCodes(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.authService.ListInviteCodes(r.Context())
+	if err != nil {
+		h.logger.Errorf("Handler: ListInviteCodes: failed to list invite codes: %v", err)
+		http.Error(w, "Failed to list invite codes", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Infof("Handler: ListInviteCodes: retrieved %d invite codes", len(invites))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invites)
+}
+
+func (h *AuthHandler) AddAllowlistEntry(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Entry    string `json:"entry"`
+		IsPrefix bool   `json:"is_prefix"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: AddAllowlistEntry: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromContext(r.Context(), "")
+	if err := h.authService.AddAllowlistEntry(r.Context(), request.Entry, request.IsPrefix, actor); err != nil {
+		h.logger.Errorf("Handler: AddAllowlistEntry: failed to allowlist %s: %v", request.Entry, err)
+		http.Error(w, "Failed to add allowlist entry", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Infof("Handler: AddAllowlistEntry: %s allowlisted by %s", request.Entry, actor)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Allowlist entry added"))
+}
+
+func (h *AuthHandler) RemoveAllowlistEntry(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Entry string `json:"entry"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: RemoveAllowlistEntry: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authService.RemoveAllowlistEntry(r.Context(), request.Entry); err != nil {
+		h.logger.Errorf("Handler: RemoveAllowlistEntry: failed to remove %s: %v", request.Entry, err)
+		if err == domain.ErrAllowlistEntryNotFound {
+			http.Error(w, "Allowlist entry not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to remove allowlist entry", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Infof("Handler: RemoveAllowlistEntry: %s removed from allowlist", request.Entry)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Allowlist entry removed"))
+}
+
+func (h *AuthHandler) ListAllowlistEntries(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.authService.ListAllowlistEntries(r.Context())
+	if err != nil {
+		h.logger.Errorf("Handler: ListAllowlistEntries: failed to list allowlist: %v", err)
+		http.Error(w, "Failed to list allowlist entries", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Infof("Handler: ListAllowlistEntries: retrieved %d allowlist entries", len(entries))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// exportFlushInterval is how many records are buffered between flushes of an
+// export to the client.
+const exportFlushInterval = 500
+
+// ExportActivities streams the tenant's activities as JSON Lines or CSV. The
+// X-Export-Cursor trailer, sent once every record has been written, resumes
+// the export after the last one. An export that fails midway is aborted so
+// that it cannot be mistaken for a complete one.
+func (h *AuthHandler) ExportActivities(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Since        time.Time `json:"since"`
+		Until        time.Time `json:"until"`
+		Format       string    `json:"format"`
+		Cursor       string    `json:"cursor"`
+		Pseudonymize bool      `json:"pseudonymize"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: ExportActivities: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	tenant, err := domain.TenantFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "Unknown tenant", http.StatusUnauthorized)
+		return
+	}
+	opts := application.ExportOptions{
+		TenantID:     tenant.ID,
+		Filter:       domain.ExportFilter{Since: request.Since, Until: request.Until},
+		Pseudonymize: request.Pseudonymize,
+	}
+	if request.Cursor != "" {
+		if opts.Filter.AfterID, err = domain.ParseExportCursor(request.Cursor); err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	format := application.ExportFormat(request.Format)
+	if format == "" {
+		format = application.ExportJSONL
+	}
+	encoder, err := application.NewActivityEncoder(w, format)
+	if err != nil {
+		http.Error(w, "Unknown export format", http.StatusBadRequest)
+		return
+	}
+
+	// Exports outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Trailer", "X-Export-Cursor")
+	if format == application.ExportCSV {
+		w.Header().Set("Content-Type", "text/csv")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	flusher, _ := w.(http.Flusher)
+
+	count := 0
+	started := false
+	lastID, err := h.auditService.ExportActivities(r.Context(), opts, func(activity *domain.Activity) error {
+		started = true
+		if err := encoder.Encode(activity); err != nil {
+			return err
+		}
+		if count++; count%exportFlushInterval == 0 && flusher != nil {
+			if err := encoder.Flush(); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = encoder.Flush()
+	}
+	if err != nil {
+		h.logger.Errorf("Handler: ExportActivities: export stopped after activity %d: %v", lastID, err)
+		if started {
+			// Drop the connection rather than end a truncated export cleanly
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Del("Trailer")
+		if err == domain.ErrPseudonymKeyNotConfigured {
+			http.Error(w, "Pseudonymisation not configured", http.StatusConflict)
+		} else {
+			http.Error(w, "Failed to export activities", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("X-Export-Cursor", domain.ExportCursor(lastID))
+
+	h.logger.Infof("Handler: ExportActivities: exported %d activities up to %d", count, lastID)
+}
+
+func (h *AuthHandler) GetOTPTimeline(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		PhoneNumber string    `json:"phone"`
+		Since       time.Time `json:"since"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.logger.Errorf("Handler: GetOTPTimeline: failed to decode request: %v", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.authService.GetOTPTimeline(r.Context(), request.PhoneNumber, request.Since)
+	if err != nil {
+		h.logger.Errorf("Handler: GetOTPTimeline: failed to get OTP timeline for phone number %s: %v", request.PhoneNumber, err)
+		http.Error(w, "Failed to get OTP timeline", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Infof("Handler: GetOTPTimeline: retrieved %d OTP events", len(events))
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(profile)
+	json.NewEncoder(w).Encode(events)
 }
@@ -0,0 +1,90 @@
+// Package requestmeta attaches the client context of each request on the auth
+// service's Connect and REST transports.
+package requestmeta
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+
+	"midaslabs/microservices/auth/internal/domain"
+)
+
+const (
+	// DeviceIDHeader carries the client's stable device identifier.
+	DeviceIDHeader = "X-Device-ID"
+	// RequestIDHeader carries the request ID. It is generated when absent and
+	// echoed on the response.
+	RequestIDHeader = "X-Request-ID"
+	// ForwardedForHeader is set by proxies in front of the service.
+	ForwardedForHeader = "X-Forwarded-For"
+)
+
+const (
+	maxRequestIDLength = 64
+	// maxDeviceIDLength matches the device_id columns.
+	maxDeviceIDLength = 128
+)
+
+// Middleware attaches domain.RequestMetadata to the request context. The
+// client IP is taken from X-Forwarded-For only when trustProxy is set, as
+// the header is otherwise under the caller's control.
+func Middleware(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if requestID == "" || len(requestID) > maxRequestIDLength {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			md := domain.RequestMetadata{
+				IP:        clientIP(r, trustProxy),
+				UserAgent: r.UserAgent(),
+				DeviceID:  deviceID(r.Header.Get(DeviceIDHeader)),
+				RequestID: requestID,
+			}
+			next.ServeHTTP(w, r.WithContext(domain.WithRequestMetadata(r.Context(), md)))
+		})
+	}
+}
+
+// deviceID truncates over-long device IDs rather than failing the request
+// when they are stored.
+func deviceID(value string) string {
+	if len(value) > maxDeviceIDLength {
+		value = value[:maxDeviceIDLength]
+	}
+	return strings.ToValidUTF8(value, "")
+}
+
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		// The left-most address is the original client
+		if forwarded := r.Header.Get(ForwardedForHeader); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := net.ParseIP(strings.TrimSpace(first)); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
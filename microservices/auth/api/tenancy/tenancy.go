@@ -0,0 +1,63 @@
+// Package tenancy resolves the tenant of each request on the auth service's
+// Connect and REST transports.
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"connectrpc.com/connect"
+
+	"midaslabs/microservices/auth/internal/domain"
+)
+
+// APIKeyHeader carries the API key identifying the calling app.
+const APIKeyHeader = "X-API-Key"
+
+// Middleware resolves the tenant from the API key header, falling back to the
+// request host and then to defaultTenantID when it is not empty. The tenant
+// is attached to the request context with domain.WithTenant.
+func Middleware(repo domain.TenantRepository, defaultTenantID string) func(http.Handler) http.Handler {
+	errorWriter := connect.NewErrorWriter()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant, err := resolve(r.Context(), repo, r, defaultTenantID)
+			if err != nil {
+				code, status := connect.CodeInternal, http.StatusInternalServerError
+				if errors.Is(err, domain.ErrTenantNotFound) {
+					code, status = connect.CodeUnauthenticated, http.StatusUnauthorized
+				}
+				if errorWriter.IsSupported(r) {
+					errorWriter.Write(w, r, connect.NewError(code, err))
+				} else {
+					http.Error(w, "Unknown tenant", status)
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(domain.WithTenant(r.Context(), tenant)))
+		})
+	}
+}
+
+func resolve(ctx context.Context, repo domain.TenantRepository, r *http.Request, defaultTenantID string) (*domain.Tenant, error) {
+	if apiKey := r.Header.Get(APIKeyHeader); apiKey != "" {
+		// An API key that does not match is an error rather than a reason
+		// to fall back to the host.
+		return repo.GetTenantByAPIKey(ctx, apiKey)
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	tenant, err := repo.GetTenantByHost(ctx, host)
+	if err == nil || !errors.Is(err, domain.ErrTenantNotFound) || defaultTenantID == "" {
+		return tenant, err
+	}
+
+	return repo.GetTenant(ctx, defaultTenantID)
+}
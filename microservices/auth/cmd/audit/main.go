@@ -0,0 +1,189 @@
+// Audit inspects the tamper-evident activity log of the auth service.
+//
+//	audit verify      walks every activity hash chain and reports the first
+//	                  broken link. With --audit-checkpoint-file set it also checks
+//	                  the chains against the latest published checkpoint.
+//	audit checkpoint  takes a checkpoint now and appends it to the
+//	                  checkpoint file.
+//	audit export      streams activities to stdout as JSON Lines or CSV and
+//	                  prints the cursor resuming it to stderr.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"midaslabs/microservices/auth/internal/application"
+	"midaslabs/microservices/auth/internal/domain"
+	infrastructure "midaslabs/microservices/auth/internal/infrastrucutre"
+
+	"github.com/ardanlabs/conf/v3"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+var build = "develop"
+
+func main() {
+	if err := run(context.Background()); err != nil {
+		fmt.Fprintln(os.Stderr, "audit:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context) error {
+	cfg := struct {
+		conf.Version
+		Args conf.Args
+
+		DB struct {
+			User       string `conf:"default:user"`
+			Password   string `conf:"default:password,mask"`
+			Host       string `conf:"default:localhost"`
+			Name       string `conf:"default:usersdb"`
+			DisableTLS bool   `conf:"default:true"`
+		}
+
+		Audit struct {
+			Tenant         string `conf:"help:limit verification and export to one tenant"`
+			CheckpointFile string `conf:"help:file checkpoints are read from and appended to"`
+			Since          string `conf:"help:export activities recorded at or after this RFC 3339 time"`
+			Until          string `conf:"help:export activities recorded before this RFC 3339 time"`
+			Format         string `conf:"default:jsonl,help:export format, jsonl or csv"`
+			Cursor         string `conf:"help:resume an export after this cursor"`
+			Pseudonymize   bool   `conf:"help:replace phone numbers with a keyed hash"`
+			PseudonymKey   string `conf:"mask"`
+		}
+	}{
+		Version: conf.Version{
+			Build: build,
+			Desc:  "AUTH audit log tool",
+		},
+	}
+
+	// Shares the AUTH prefix so the service's database settings apply.
+	const prefix = "AUTH"
+	help, err := conf.Parse(prefix, &cfg)
+	if err != nil {
+		if errors.Is(err, conf.ErrHelpWanted) {
+			fmt.Println(help)
+			return nil
+		}
+		return fmt.Errorf("parsing config: %w", err)
+	}
+
+	db, err := infrastructure.OpenDB(infrastructure.DBConfig{
+		User:       cfg.DB.User,
+		Password:   cfg.DB.Password,
+		Host:       cfg.DB.Host,
+		Name:       cfg.DB.Name,
+		DisableTLS: cfg.DB.DisableTLS,
+	})
+	if err != nil {
+		return fmt.Errorf("connecting to db: %w", err)
+	}
+	defer db.Close()
+
+	activityRepo := infrastructure.NewPostgresActivityRepository(db)
+	auditService := application.NewAuditService(activityRepo, infrastructure.NewFileCheckpointSink(cfg.Audit.CheckpointFile), []byte(cfg.Audit.PseudonymKey))
+
+	switch cfg.Args.Num(0) {
+	case "verify":
+		return verify(ctx, auditService, cfg.Audit.Tenant, cfg.Audit.CheckpointFile)
+	case "checkpoint":
+		if cfg.Audit.CheckpointFile == "" {
+			return errors.New("checkpoint needs --audit-checkpoint-file")
+		}
+		checkpoint, err := auditService.TakeCheckpoint(ctx)
+		if err != nil {
+			return fmt.Errorf("taking checkpoint: %w", err)
+		}
+		fmt.Printf("checkpoint of %d chains up to activity %d: %s\n", checkpoint.Chains, checkpoint.LastActivityID, checkpoint.Digest)
+		return nil
+	case "export":
+		opts := application.ExportOptions{
+			TenantID:     cfg.Audit.Tenant,
+			Pseudonymize: cfg.Audit.Pseudonymize,
+		}
+		if opts.Filter.Since, err = parseTime(cfg.Audit.Since); err != nil {
+			return fmt.Errorf("parsing since: %w", err)
+		}
+		if opts.Filter.Until, err = parseTime(cfg.Audit.Until); err != nil {
+			return fmt.Errorf("parsing until: %w", err)
+		}
+		if cfg.Audit.Cursor != "" {
+			if opts.Filter.AfterID, err = domain.ParseExportCursor(cfg.Audit.Cursor); err != nil {
+				return err
+			}
+		}
+		return export(ctx, auditService, opts, application.ExportFormat(cfg.Audit.Format))
+	default:
+		return fmt.Errorf("unknown command %q, want verify, checkpoint or export", cfg.Args.Num(0))
+	}
+}
+
+func verify(ctx context.Context, auditService *application.AuditService, tenantID, checkpointFile string) error {
+	chainBreak, err := auditService.VerifyChains(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("verifying chains: %w", err)
+	}
+	if chainBreak != nil {
+		return fmt.Errorf("broken chain: %s", chainBreak)
+	}
+	fmt.Println("all chains intact")
+
+	if checkpointFile == "" {
+		return nil
+	}
+	checkpoints, err := infrastructure.ReadCheckpoints(checkpointFile)
+	if err != nil {
+		return fmt.Errorf("reading checkpoints: %w", err)
+	}
+	if len(checkpoints) == 0 {
+		return errors.New("no checkpoints published yet")
+	}
+	latest := checkpoints[len(checkpoints)-1]
+	ok, err := auditService.VerifyCheckpoint(ctx, latest)
+	if err != nil {
+		return fmt.Errorf("verifying checkpoint: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("chains no longer match the checkpoint taken at %s", latest.TakenAt)
+	}
+	fmt.Printf("chains match the checkpoint taken at %s\n", latest.TakenAt)
+	return nil
+}
+
+func export(ctx context.Context, auditService *application.AuditService, opts application.ExportOptions, format application.ExportFormat) error {
+	out := bufio.NewWriter(os.Stdout)
+	encoder, err := application.NewActivityEncoder(out, format)
+	if err != nil {
+		return err
+	}
+
+	lastID, err := auditService.ExportActivities(ctx, opts, encoder.Encode)
+
+	if flushErr := encoder.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	if flushErr := out.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return fmt.Errorf("exporting activities: %w", err)
+	}
+
+	// The cursor is only printed once every record has been written
+	fmt.Fprintf(os.Stderr, "cursor: %s\n", domain.ExportCursor(lastID))
+	return nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"context"
+	"midaslabs/microservices/auth/internal/domain"
+	"time"
+)
+
+// recordActivity stamps the activity with the client context of the request
+// and stores it. Activities without an outcome are recorded as successes.
+func (s *AuthService) recordActivity(ctx context.Context, activity *domain.Activity) error {
+	activity.Client = domain.RequestMetadataFromContext(ctx)
+	if activity.Outcome == "" {
+		activity.Outcome = domain.OutcomeSuccess
+	}
+	return s.activityRepo.RecordActivity(ctx, activity)
+}
+
+// recordFailure records the failed activity when *errp is set. It is meant to
+// be deferred, so it never replaces the error the caller returns; callers may
+// fill in the activity, such as its risk assessment, before returning.
+func (s *AuthService) recordFailure(ctx context.Context, activity *domain.Activity, errp *error) {
+	if *errp == nil {
+		return
+	}
+
+	// Log the failed activity
+	activity.Outcome = domain.OutcomeFailure
+	activity.FailureReason = domain.ErrorCode(*errp)
+	activity.Timestamp = time.Now()
+	_ = s.recordActivity(ctx, activity)
+}
+
+const (
+	defaultActivityPageSize = 50
+	maxActivityPageSize     = 200
+)
+
+// ListActivities returns a page of activity history for filter.PhoneNumber.
+// Callers check that the caller may read it.
+func (s *AuthService) ListActivities(ctx context.Context, filter domain.ActivityFilter) (*domain.ActivityPage, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultActivityPageSize
+	}
+	if limit > maxActivityPageSize {
+		limit = maxActivityPageSize
+	}
+
+	// Fetch one extra row to learn whether another page follows
+	filter.Limit = limit + 1
+	activities, err := s.activityRepo.ListActivities(ctx, tenant.ID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.ActivityPage{Activities: activities}
+	if len(activities) > limit {
+		page.Activities = activities[:limit]
+		page.NextCursor = domain.CursorOf(activities[limit-1]).Encode()
+	}
+	return page, nil
+}
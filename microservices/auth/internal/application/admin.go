@@ -0,0 +1,197 @@
+package application
+
+import (
+	"context"
+	"midaslabs/microservices/auth/internal/domain"
+	"time"
+)
+
+// SuspendUser freezes the account of the given phone number. Suspended users
+// can neither start nor complete a login until they are reinstated.
+func (s *AuthService) SuspendUser(ctx context.Context, phoneNumber, reason, actor string) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
+	if err != nil {
+		return err
+	}
+
+	if err := user.Suspend(reason, actor); err != nil {
+		return err
+	}
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		return s.publishUserEvent(ctx, domain.UserUpdated, user, reason, actor)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Drop any pending OTP so an in-flight login cannot be completed
+	if err := s.otpRepo.DeleteOTP(ctx, tenant.ID, phoneNumber); err != nil {
+		return err
+	}
+
+	// Log the suspension activity
+	activity := &domain.Activity{
+		TenantID:    tenant.ID,
+		PhoneNumber: phoneNumber,
+		Type:        domain.ActivitySuspend,
+		Actor:       actor,
+		Timestamp:   time.Now(),
+	}
+	if err := s.recordActivity(ctx, activity); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReinstateUser lifts the suspension of the given phone number.
+func (s *AuthService) ReinstateUser(ctx context.Context, phoneNumber, actor string) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
+	if err != nil {
+		return err
+	}
+
+	if err := user.Reinstate(); err != nil {
+		return err
+	}
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		return s.publishUserEvent(ctx, domain.UserUpdated, user, "reinstated", actor)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Log the reinstatement activity
+	activity := &domain.Activity{
+		TenantID:    tenant.ID,
+		PhoneNumber: phoneNumber,
+		Type:        domain.ActivityReinstate,
+		Actor:       actor,
+		Timestamp:   time.Now(),
+	}
+	if err := s.recordActivity(ctx, activity); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListSuspendedUsers returns all currently suspended users, most recently suspended first.
+func (s *AuthService) ListSuspendedUsers(ctx context.Context) ([]*domain.User, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.ListUsersByStatus(ctx, tenant.ID, domain.UserStatusSuspended)
+}
+
+// AssignRole grants a role to the given phone number.
+func (s *AuthService) AssignRole(ctx context.Context, phoneNumber, role, actor string) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber); err != nil {
+		return err
+	}
+	if _, err := s.roleRepo.GetRole(ctx, role); err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.AssignRole(ctx, tenant.ID, phoneNumber, role, actor); err != nil {
+		return err
+	}
+
+	// Log the role assignment activity
+	activity := &domain.Activity{
+		TenantID:    tenant.ID,
+		PhoneNumber: phoneNumber,
+		Type:        domain.ActivityRoleAssign,
+		Actor:       actor,
+		Metadata:    map[string]string{"role": role},
+		Timestamp:   time.Now(),
+	}
+	if err := s.recordActivity(ctx, activity); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeRole removes a role from the given phone number, along with the
+// access tokens carrying its permissions.
+func (s *AuthService) RevokeRole(ctx context.Context, phoneNumber, role, actor string) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
+	if err != nil {
+		return err
+	}
+
+	user.RevokeTokens()
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.roleRepo.RevokeRole(ctx, tenant.ID, phoneNumber, role); err != nil {
+			return err
+		}
+		return s.userRepo.UpdateUser(ctx, user)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Log the role revocation activity
+	activity := &domain.Activity{
+		TenantID:    tenant.ID,
+		PhoneNumber: phoneNumber,
+		Type:        domain.ActivityRoleRevoke,
+		Actor:       actor,
+		Metadata:    map[string]string{"role": role},
+		Timestamp:   time.Now(),
+	}
+	if err := s.recordActivity(ctx, activity); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetUser returns the user of the given phone number.
+func (s *AuthService) GetUser(ctx context.Context, phoneNumber string) (*domain.User, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
+}
+
+// GetUserRoles returns the roles held by the given phone number.
+func (s *AuthService) GetUserRoles(ctx context.Context, phoneNumber string) ([]*domain.Role, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.roleRepo.GetUserRoles(ctx, tenant.ID, phoneNumber)
+}
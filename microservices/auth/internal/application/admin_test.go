@@ -0,0 +1,100 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"midaslabs/microservices/auth/api/authz"
+	"midaslabs/microservices/auth/internal/domain"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeUserRepo holds users by phone number, returning copies as a database
+// would.
+type fakeUserRepo struct {
+	domain.UserRepository
+	users map[string]*domain.User
+}
+
+func (r *fakeUserRepo) GetUser(ctx context.Context, tenantID, phoneNumber string) (*domain.User, error) {
+	user, ok := r.users[phoneNumber]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *fakeUserRepo) UpdateUser(ctx context.Context, user *domain.User) error {
+	copied := *user
+	r.users[user.PhoneNumber] = &copied
+	return nil
+}
+
+// fakeRoleRepo holds the roles of each phone number.
+type fakeRoleRepo struct {
+	domain.RoleRepository
+	roles map[string][]*domain.Role
+}
+
+func (r *fakeRoleRepo) RevokeRole(ctx context.Context, tenantID, phoneNumber, role string) error {
+	r.roles[phoneNumber] = slices.DeleteFunc(r.roles[phoneNumber], func(held *domain.Role) bool {
+		return held.Name == role
+	})
+	return nil
+}
+
+func (r *fakeRoleRepo) GetUserRoles(ctx context.Context, tenantID, phoneNumber string) ([]*domain.Role, error) {
+	return r.roles[phoneNumber], nil
+}
+
+// fakeActivityRepo records activities.
+type fakeActivityRepo struct {
+	domain.ActivityRepository
+	activities []*domain.Activity
+}
+
+func (r *fakeActivityRepo) RecordActivity(ctx context.Context, activity *domain.Activity) error {
+	r.activities = append(r.activities, activity)
+	return nil
+}
+
+func tenantContext() context.Context {
+	return domain.WithTenant(context.Background(), &domain.Tenant{ID: "acme"})
+}
+
+// newTokens returns tokens looking users up through s.
+func newTokens(t *testing.T, s *AuthService) *authz.Tokens {
+	t.Helper()
+	tokens, err := authz.NewTokens([]byte("0123456789abcdef0123456789abcdef"), time.Minute, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+func TestRevokeRoleRevokesTokens(t *testing.T) {
+	users := &fakeUserRepo{users: map[string]*domain.User{"+15550199": domain.NewUser("acme", "+15550199")}}
+	roles := &fakeRoleRepo{roles: map[string][]*domain.Role{
+		"+15550199": {{Name: "admin", Permissions: []domain.Permission{domain.PermissionUsersSuspend}}},
+	}}
+	s := &AuthService{userRepo: users, roleRepo: roles, activityRepo: &fakeActivityRepo{}, transactor: fakeTransactor{}}
+	tokens := newTokens(t, s)
+	ctx := tenantContext()
+
+	token, _, err := tokens.Issue(ctx, "+15550199")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := tokens.Verify(ctx, token); err != nil || !claims.HasPermission(domain.PermissionUsersSuspend) {
+		t.Fatalf("token before revocation: claims %v, err %v", claims, err)
+	}
+
+	if err := s.RevokeRole(ctx, "+15550199", "admin", "+15550101"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Verify(ctx, token); !errors.Is(err, authz.ErrUnauthenticated) {
+		t.Errorf("token carrying the revoked role: err = %v, want %v", err, authz.ErrUnauthenticated)
+	}
+}
@@ -5,157 +5,243 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	eventsv1 "midaslabs/gen/events/v1"
 	"midaslabs/microservices/auth/internal/domain"
+	"midaslabs/sdk/contracts"
 	"time"
-)
 
-const otpValidityDuration = 5 * time.Minute
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
 
 // AuthService handles user authentication and OTP operations.
 type AuthService struct {
-	userRepo      domain.UserRepository
-	activityRepo  domain.ActivityRepository
-	otpRepo       domain.OTPRepository
-	messageBroker domain.MessageBroker
+	userRepo       domain.UserRepository
+	activityRepo   domain.ActivityRepository
+	otpRepo        domain.OTPRepository
+	otpEventRepo   domain.OTPEventRepository
+	roleRepo       domain.RoleRepository
+	inviteRepo     domain.InviteCodeRepository
+	allowlistRepo  domain.AllowlistRepository
+	recoveryRepo   domain.RecoveryCodeRepository
+	clientRepo     domain.KnownClientRepository
+	alertRepo      domain.LoginAlertRepository
+	riskEngine     domain.RiskEngine
+	stepUpFallback StepUpFallback
+	outbox         domain.OutboxRepository
+	transactor     domain.Transactor
+	userEvents     *UserEvents
 }
 
-func NewAuthService(userRepo domain.UserRepository, activityRepo domain.ActivityRepository, otpRepo domain.OTPRepository, messageBroker domain.MessageBroker) *AuthService {
+func NewAuthService(userRepo domain.UserRepository, activityRepo domain.ActivityRepository, otpRepo domain.OTPRepository, otpEventRepo domain.OTPEventRepository, roleRepo domain.RoleRepository, inviteRepo domain.InviteCodeRepository, allowlistRepo domain.AllowlistRepository, recoveryRepo domain.RecoveryCodeRepository, clientRepo domain.KnownClientRepository, alertRepo domain.LoginAlertRepository, riskEngine domain.RiskEngine, stepUpFallback StepUpFallback, outbox domain.OutboxRepository, transactor domain.Transactor, userEvents *UserEvents) *AuthService {
 	return &AuthService{
-		userRepo:      userRepo,
-		activityRepo:  activityRepo,
-		otpRepo:       otpRepo,
-		messageBroker: messageBroker,
+		userRepo:       userRepo,
+		activityRepo:   activityRepo,
+		otpRepo:        otpRepo,
+		otpEventRepo:   otpEventRepo,
+		roleRepo:       roleRepo,
+		inviteRepo:     inviteRepo,
+		allowlistRepo:  allowlistRepo,
+		recoveryRepo:   recoveryRepo,
+		clientRepo:     clientRepo,
+		alertRepo:      alertRepo,
+		riskEngine:     riskEngine,
+		stepUpFallback: stepUpFallback,
+		outbox:         outbox,
+		transactor:     transactor,
+		userEvents:     userEvents,
 	}
 }
 
 // SignUpWithPhoneNumber handles user signup and requests OTP for verification.
-func (s *AuthService) SignUpWithPhoneNumber(ctx context.Context, phoneNumber string) error {
-	_, err := s.userRepo.GetUser(ctx, phoneNumber)
-	if err == nil {
-		return domain.ErrUserAlreadyExists
-	}
-	if !errors.Is(err, domain.ErrUserNotFound) {
+// The invite code is only consulted when the tenant's signup policy requires one.
+func (s *AuthService) SignUpWithPhoneNumber(ctx context.Context, phoneNumber, inviteCode string) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
 		return err
 	}
 
-	user := domain.NewUser(phoneNumber)
-	if err := s.userRepo.AddUser(ctx, user); err != nil {
-		return err
+	if !tenant.AllowsPhoneNumber(phoneNumber) {
+		return domain.ErrCountryNotAllowed
 	}
 
-	// Request OTP for verification
-	if err := s.requestNewOTP(ctx, phoneNumber); err != nil {
-		return err
-	}
-
-	// Log the signup activity
-	activity := &domain.Activity{
-		PhoneNumber: phoneNumber,
-		Type:        domain.ActivitySignup,
-		Timestamp:   time.Now(),
+	_, err = s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
+	if err == nil {
+		return domain.ErrUserAlreadyExists
 	}
-	if err := s.activityRepo.RecordActivity(ctx, activity); err != nil {
+	if !errors.Is(err, domain.ErrUserNotFound) {
 		return err
 	}
 
-	return nil
+	// The user and their first OTP are created together, so a failure never
+	// leaves a user who cannot receive a code, nor spends an invite code
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Enforce the tenant's signup policy
+		if err := s.checkSignupPolicy(ctx, tenant, phoneNumber, inviteCode); err != nil {
+			return err
+		}
+
+		user := domain.NewUser(tenant.ID, phoneNumber)
+		if tenant.SignupPolicy == domain.SignupPolicyInviteCode {
+			user.InviteCode = inviteCode
+		}
+		if err := s.userRepo.AddUser(ctx, user); err != nil {
+			return err
+		}
+		if err := s.publishUserEvent(ctx, domain.UserSignedUp, user, "", ""); err != nil {
+			return err
+		}
+
+		// Request OTP for verification
+		if err := s.requestNewOTP(ctx, tenant, phoneNumber); err != nil {
+			return err
+		}
+
+		// Log the signup activity
+		activity := &domain.Activity{
+			TenantID:    tenant.ID,
+			PhoneNumber: phoneNumber,
+			Type:        domain.ActivitySignup,
+			Timestamp:   time.Now(),
+		}
+		if user.InviteCode != "" {
+			activity.Metadata = map[string]string{"invite_code": user.InviteCode}
+		}
+		return s.recordActivity(ctx, activity)
+	})
+}
+
+// requestOTP generates a new OTP, deletes any existing one, and queues it in
+// the outbox for the OTP service. The OTP is stored and queued in one
+// transaction.
+func (s *AuthService) requestNewOTP(ctx context.Context, tenant *domain.Tenant, phoneNumber string) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		return s.storeAndQueueOTP(ctx, tenant, phoneNumber)
+	})
 }
 
-// requestOTP generates a new OTP, deletes any existing one, and sends it via the message broker.
-func (s *AuthService) requestNewOTP(ctx context.Context, phoneNumber string) error {
+func (s *AuthService) storeAndQueueOTP(ctx context.Context, tenant *domain.Tenant, phoneNumber string) error {
 	// Delete old OTP if exists
-	if err := s.otpRepo.DeleteOTP(ctx, phoneNumber); err != nil && !errors.Is(err, domain.ErrOTPNotFound) {
+	previous, err := s.otpRepo.GetOTP(ctx, tenant.ID, phoneNumber)
+	if err != nil && !errors.Is(err, domain.ErrOTPNotFound) {
 		return err
 	}
+	if previous != nil {
+		if err := s.otpRepo.DeleteOTP(ctx, tenant.ID, phoneNumber); err != nil {
+			return err
+		}
+		if err := s.appendOTPEvent(ctx, previous, domain.OTPEventExpired, "superseded"); err != nil {
+			return err
+		}
+	}
 
 	// Generate new OTP
-	otpCode, err := generateOTP()
+	otpCode, err := generateOTP(tenant.OTPCodeLength())
+	if err != nil {
+		return err
+	}
+	otpID, err := generateOTPID()
 	if err != nil {
 		return err
 	}
 
-	expiration := time.Now().Add(otpValidityDuration)
+	otp := &domain.OTP{
+		ID:          otpID,
+		TenantID:    tenant.ID,
+		PhoneNumber: phoneNumber,
+		Code:        otpCode,
+		Expiration:  time.Now().Add(tenant.OTPValidity()),
+	}
 
 	// Save new OTP to the database
-	if err := s.otpRepo.StoreOTP(ctx, phoneNumber, otpCode, expiration); err != nil {
+	if err := s.otpRepo.StoreOTP(ctx, otp); err != nil {
+		return err
+	}
+	if err := s.appendOTPEvent(ctx, otp, domain.OTPEventIssued, "expires "+otp.Expiration.UTC().Format(time.RFC3339)); err != nil {
 		return err
 	}
 
-	// Publish OTP message
-	if err := s.publishSendOTPEvent(ctx, phoneNumber, otpCode); err != nil {
+	// Queue OTP message
+	if err := s.publishSendOTPEvent(ctx, tenant, otp); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// publishSendOTPEvent sends an OTP message to the message broker.
-func (s *AuthService) publishSendOTPEvent(ctx context.Context, phoneNumber, otpCode string) error {
+// publishSendOTPEvent queues an OTP message for the message broker.
+func (s *AuthService) publishSendOTPEvent(ctx context.Context, tenant *domain.Tenant, otp *domain.OTP) error {
 	// Create a message payload
-	event := domain.OTPVerificationEvent{
-		PhoneNumber: phoneNumber,
-		OTPCode:     otpCode,
-	}
-	message, err := event.Serialize()
+	message, err := contracts.Marshal(&eventsv1.OTPVerificationEvent{
+		TenantId:    tenant.ID,
+		OtpId:       otp.ID,
+		PhoneNumber: otp.PhoneNumber,
+		OtpCode:     otp.Code,
+		Sender:      tenant.SMSSender,
+		Message:     tenant.RenderSMS(otp.Code),
+		ExpiresAt:   timestamppb.New(otp.Expiration),
+	})
 	if err != nil {
 		return err
 	}
 
-	// Publish message to the broker
-	if err := s.messageBroker.Publish(ctx, "verification", message); err != nil {
+	// Queue message for the broker, where it is useless once the code expires
+	if err := s.outbox.EnqueueExpiringMessage(ctx, otpTopic, message, otp.Expiration); err != nil {
 		return err
 	}
 	return nil
 }
 
-func generateOTP() (string, error) {
-	b := make([]byte, 4) // 4 bytes to generate an OTP of 8 hexadecimal characters
+func generateOTP(length int) (string, error) {
+	b := make([]byte, (length+1)/2) // each byte yields two hexadecimal characters
 	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
-	return hex.EncodeToString(b), nil
+	return hex.EncodeToString(b)[:length], nil
 }
 
 // VerifyPhoneNumber verifies the OTP for the given phone number after signup.
-func (s *AuthService) VerifyPhoneNumber(ctx context.Context, phoneNumber, otp string) error {
-	// Retrieve OTP from the database
-	storedOTP, expiration, err := s.otpRepo.GetOTP(ctx, phoneNumber)
+func (s *AuthService) VerifyPhoneNumber(ctx context.Context, phoneNumber, otp string) (err error) {
+	tenant, err := domain.TenantFromContext(ctx)
 	if err != nil {
 		return err
 	}
+	defer s.recordFailure(ctx, &domain.Activity{TenantID: tenant.ID, PhoneNumber: phoneNumber, Type: domain.ActivityVerify}, &err)
 
-	// Check if OTP is expired
-	if time.Now().After(expiration) {
-		return domain.ErrOTPExpired
-	}
-
-	// Check if OTP matches
-	if storedOTP != otp {
-		return domain.ErrInvalidOTP
+	storedOTP, err := s.checkOTP(ctx, tenant, phoneNumber, otp)
+	if err != nil {
+		return err
 	}
 
 	// Verify user
-	user, err := s.userRepo.GetUser(ctx, phoneNumber)
+	user, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
 	if err != nil {
 		return err
 	}
-	user.Verify()
-	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		user.Verify()
+		if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		return s.publishUserEvent(ctx, domain.UserVerified, user, "", "")
+	})
+	if err != nil {
 		return err
 	}
 
 	// Delete OTP after verification
-	if err := s.otpRepo.DeleteOTP(ctx, phoneNumber); err != nil {
+	if err := s.otpRepo.DeleteOTP(ctx, tenant.ID, phoneNumber); err != nil {
 		return err
 	}
+	s.recordOTPEvent(ctx, storedOTP, domain.OTPEventConsumed, "")
 
 	// Log the verification activity
 	activity := &domain.Activity{
+		TenantID:    tenant.ID,
 		PhoneNumber: phoneNumber,
 		Type:        domain.ActivityVerify,
 		Timestamp:   time.Now(),
 	}
-	if err := s.activityRepo.RecordActivity(ctx, activity); err != nil {
+	if err := s.recordActivity(ctx, activity); err != nil {
 		return err
 	}
 
@@ -163,8 +249,19 @@ func (s *AuthService) VerifyPhoneNumber(ctx context.Context, phoneNumber, otp st
 }
 
 // LoginInitiate initiates the login process by sending an OTP to the user's phone number.
-func (s *AuthService) LoginInitiate(ctx context.Context, phoneNumber string) error {
-	user, err := s.userRepo.GetUser(ctx, phoneNumber)
+func (s *AuthService) LoginInitiate(ctx context.Context, phoneNumber string) (err error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	failure := &domain.Activity{TenantID: tenant.ID, PhoneNumber: phoneNumber, Type: domain.ActivityLogin}
+	defer s.recordFailure(ctx, failure, &err)
+
+	if !tenant.AllowsPhoneNumber(phoneNumber) {
+		return domain.ErrCountryNotAllowed
+	}
+
+	user, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
 	if err != nil {
 		return domain.ErrUserNotFound
 	}
@@ -173,44 +270,63 @@ func (s *AuthService) LoginInitiate(ctx context.Context, phoneNumber string) err
 		return domain.ErrUserNotVerified
 	}
 
+	if user.IsSuspended() {
+		return domain.ErrUserSuspended
+	}
+
+	// A number that has been idle for long may have been reassigned by the carrier
+	if !user.RequiresReverification() && user.IsDormant(tenant.DormancyPeriod, time.Now()) {
+		if err := s.requireReverification(ctx, user, domain.ReverificationReasonDormant); err != nil {
+			return err
+		}
+	}
+
+	// Score the attempt before spending an SMS on it. A challenge is met when
+	// the login completes, so the OTP is still sent.
+	failure.Risk, err = s.assessLogin(ctx, domain.LoginStageInitiate, tenant, user)
+	if err != nil {
+		return err
+	}
+
 	// Request OTP for login
-	if err := s.requestNewOTP(ctx, phoneNumber); err != nil {
+	if err := s.requestNewOTP(ctx, tenant, phoneNumber); err != nil {
 		return err
 	}
 
 	// Log the login initiation activity
 	activity := &domain.Activity{
+		TenantID:    tenant.ID,
 		PhoneNumber: phoneNumber,
 		Type:        domain.ActivityLogin,
+		Risk:        failure.Risk,
 		Timestamp:   time.Now(),
 	}
-	if err := s.activityRepo.RecordActivity(ctx, activity); err != nil {
+	if err := s.recordActivity(ctx, activity); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// LoginWithPhoneNumberAndOTP verifies the OTP and logs the user in.
-func (s *AuthService) ValidatePhoneNumberLogin(ctx context.Context, phoneNumber, otp string) error {
-	// Retrieve OTP from the database
-	storedOTP, expiration, err := s.otpRepo.GetOTP(ctx, phoneNumber)
+// LoginWithPhoneNumberAndOTP verifies the OTP and logs the user in. Accounts
+// awaiting reverification, and logins the risk engine challenges, must also
+// present a recovery code. Challenged users without recovery codes are
+// handled by the step-up fallback.
+func (s *AuthService) ValidatePhoneNumberLogin(ctx context.Context, phoneNumber, otp, recoveryCode string) (err error) {
+	tenant, err := domain.TenantFromContext(ctx)
 	if err != nil {
 		return err
 	}
+	failure := &domain.Activity{TenantID: tenant.ID, PhoneNumber: phoneNumber, Type: domain.ActivityLogin}
+	defer s.recordFailure(ctx, failure, &err)
 
-	// Check if OTP is expired
-	if time.Now().After(expiration) {
-		return domain.ErrOTPExpired
-	}
-
-	// Check if OTP matches
-	if storedOTP != otp {
-		return domain.ErrInvalidOTP
+	storedOTP, err := s.checkOTP(ctx, tenant, phoneNumber, otp)
+	if err != nil {
+		return err
 	}
 
 	// Verify user
-	user, err := s.userRepo.GetUser(ctx, phoneNumber)
+	user, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
 	if err != nil {
 		return err
 	}
@@ -219,18 +335,87 @@ func (s *AuthService) ValidatePhoneNumberLogin(ctx context.Context, phoneNumber,
 		return domain.ErrUserNotVerified
 	}
 
+	if user.IsSuspended() {
+		return domain.ErrUserSuspended
+	}
+
+	failure.Risk, err = s.assessLogin(ctx, domain.LoginStageComplete, tenant, user)
+	if err != nil {
+		return err
+	}
+
+	// Dormant accounts and risky logins need a second factor on top of the SMS OTP
+	challenged := failure.Risk.Decision == domain.RiskChallenge
+	stepUpSkipped := false
+	switch {
+	case user.RequiresReverification() && recoveryCode == "":
+		if err := s.reverifyWithoutCode(ctx, tenant, user); err != nil {
+			return err
+		}
+	case (user.RequiresReverification() || challenged) && recoveryCode != "":
+		if err := s.recoveryRepo.ConsumeRecoveryCode(ctx, tenant.ID, phoneNumber, hashRecoveryCode(recoveryCode)); err != nil {
+			return err
+		}
+	case challenged:
+		hasCodes, err := s.recoveryRepo.HasRecoveryCodes(ctx, tenant.ID, phoneNumber)
+		if err != nil {
+			return err
+		}
+		if hasCodes {
+			return domain.ErrStepUpRequired
+		}
+		// Users without recovery codes have no second factor to step up
+		// with, so the login is denied unless the SMS OTP may do
+		if s.stepUpFallback != StepUpFallbackAllow {
+			failure.Metadata = map[string]string{"step_up": "no_second_factor"}
+			return domain.ErrLoginDenied
+		}
+		stepUpSkipped = true
+	}
+
 	// Delete OTP after verification
-	if err := s.otpRepo.DeleteOTP(ctx, phoneNumber); err != nil {
+	if err := s.otpRepo.DeleteOTP(ctx, tenant.ID, phoneNumber); err != nil {
+		return err
+	}
+	s.recordOTPEvent(ctx, storedOTP, domain.OTPEventConsumed, "")
+
+	firstLogin := user.LastLoginAt == nil
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		user.RecordLogin()
+		if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		return s.publishUserEvent(ctx, domain.UserLoggedIn, user, "", "")
+	})
+	if err != nil {
+		return err
+	}
+
+	// Alert the user to logins from unfamiliar devices or networks
+	alert, err := s.noticeNewClient(ctx, tenant, user, firstLogin)
+	if err != nil {
 		return err
 	}
 
 	// Log the login activity
 	activity := &domain.Activity{
+		TenantID:    tenant.ID,
 		PhoneNumber: phoneNumber,
 		Type:        domain.ActivityLogin,
+		Risk:        failure.Risk,
 		Timestamp:   time.Now(),
 	}
-	if err := s.activityRepo.RecordActivity(ctx, activity); err != nil {
+	metadata := map[string]string{}
+	if alert != nil {
+		metadata["alert_id"] = alert.ID
+	}
+	if stepUpSkipped {
+		metadata["step_up"] = "no_second_factor"
+	}
+	if len(metadata) > 0 {
+		activity.Metadata = metadata
+	}
+	if err := s.recordActivity(ctx, activity); err != nil {
 		return err
 	}
 
@@ -239,18 +424,24 @@ func (s *AuthService) ValidatePhoneNumberLogin(ctx context.Context, phoneNumber,
 
 // GetProfile retrieves the profile information of the user.
 func (s *AuthService) GetProfile(ctx context.Context, phoneNumber string) (*domain.User, error) {
-	user, err := s.userRepo.GetUser(ctx, phoneNumber)
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
 	if err != nil {
 		return nil, err
 	}
 
 	// Log the profile retrieval activity
 	activity := &domain.Activity{
+		TenantID:    tenant.ID,
 		PhoneNumber: phoneNumber,
 		Type:        domain.ActivityUpdate,
 		Timestamp:   time.Now(),
 	}
-	if err := s.activityRepo.RecordActivity(ctx, activity); err != nil {
+	if err := s.recordActivity(ctx, activity); err != nil {
 		return nil, err
 	}
 
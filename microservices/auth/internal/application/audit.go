@@ -0,0 +1,119 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"midaslabs/microservices/auth/internal/domain"
+	"time"
+)
+
+// checkpointSettleDelay keeps checkpoints clear of activities whose
+// transactions may still be in flight.
+const checkpointSettleDelay = time.Minute
+
+var errChainBroken = errors.New("chain broken")
+
+// AuditService verifies, checkpoints and exports the activity audit log.
+type AuditService struct {
+	activityRepo domain.ActivityRepository
+	sink         domain.CheckpointSink
+	pseudonymKey []byte
+}
+
+func NewAuditService(activityRepo domain.ActivityRepository, sink domain.CheckpointSink, pseudonymKey []byte) *AuditService {
+	return &AuditService{
+		activityRepo: activityRepo,
+		sink:         sink,
+		pseudonymKey: pseudonymKey,
+	}
+}
+
+// VerifyChains walks every chain of the tenant, or of all tenants when
+// tenantID is empty, and returns the first broken link, or nil when all
+// chains are intact. Activities recorded before chaining was introduced
+// carry no hash and are skipped until the first hashed one of each chain.
+// Chains trimmed by retention must continue from their anchor.
+func (s *AuditService) VerifyChains(ctx context.Context, tenantID string) (*domain.ChainBreak, error) {
+	// Chains whose oldest activities were purged continue from an anchor
+	anchors, err := s.activityRepo.ChainAnchors(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	anchorHashes := make(map[[2]string]string, len(anchors))
+	for _, anchor := range anchors {
+		anchorHashes[[2]string{anchor.TenantID, anchor.PhoneNumber}] = anchor.Hash
+	}
+
+	var (
+		chainBreak              *domain.ChainBreak
+		tenant, phone, prevHash string
+		chained                 bool
+	)
+	err = s.activityRepo.WalkActivities(ctx, tenantID, func(activity *domain.Activity) error {
+		if activity.TenantID != tenant || activity.PhoneNumber != phone {
+			tenant, phone = activity.TenantID, activity.PhoneNumber
+			prevHash = anchorHashes[[2]string{tenant, phone}]
+			chained = prevHash != ""
+		}
+
+		reason := ""
+		switch {
+		case activity.Hash == "" && chained:
+			reason = "activity is missing its hash"
+		case activity.Hash == "":
+			return nil
+		case activity.PrevHash != prevHash:
+			reason = "previous hash does not match the preceding activity"
+		case activity.HashVersion < 1 || activity.HashVersion > domain.ActivityHashVersion:
+			reason = "unknown hash version"
+		case activity.ComputeHash(activity.PrevHash) != activity.Hash:
+			reason = "contents do not match the hash"
+		}
+		if reason != "" {
+			chainBreak = &domain.ChainBreak{
+				TenantID:    activity.TenantID,
+				PhoneNumber: activity.PhoneNumber,
+				ActivityID:  activity.ID,
+				Reason:      reason,
+			}
+			return errChainBroken
+		}
+
+		prevHash, chained = activity.Hash, true
+		return nil
+	})
+	if err != nil && !errors.Is(err, errChainBroken) {
+		return nil, err
+	}
+	return chainBreak, nil
+}
+
+// TakeCheckpoint digests the head of every chain and publishes it to the
+// checkpoint sink.
+func (s *AuditService) TakeCheckpoint(ctx context.Context) (*domain.AuditCheckpoint, error) {
+	now := time.Now()
+	lastID, err := s.activityRepo.SettledActivityID(ctx, now.Add(-checkpointSettleDelay))
+	if err != nil {
+		return nil, err
+	}
+	heads, err := s.activityRepo.ChainHeads(ctx, lastID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := domain.NewAuditCheckpoint(heads, lastID, now)
+	if err := s.sink.PublishCheckpoint(ctx, checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// VerifyCheckpoint reports whether the chains still match a checkpoint
+// published earlier.
+func (s *AuditService) VerifyCheckpoint(ctx context.Context, checkpoint *domain.AuditCheckpoint) (bool, error) {
+	heads, err := s.activityRepo.ChainHeads(ctx, checkpoint.LastActivityID)
+	if err != nil {
+		return false, err
+	}
+	return domain.DigestChainHeads(heads) == checkpoint.Digest, nil
+}
@@ -0,0 +1,184 @@
+package application
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"midaslabs/microservices/auth/internal/domain"
+	"strconv"
+	"time"
+)
+
+// ExportFormat names an encoding of exported activities.
+type ExportFormat string
+
+const (
+	ExportJSONL ExportFormat = "jsonl"
+	ExportCSV   ExportFormat = "csv"
+)
+
+// ExportOptions selects and shapes the activities of an export.
+type ExportOptions struct {
+	TenantID string
+	Filter   domain.ExportFilter
+	// Pseudonymize replaces phone numbers with a keyed hash, so records of
+	// one user can still be correlated without revealing the number. The
+	// chain hashes and risk assessments are left out, as the hashes cover
+	// the raw number and could be brute-forced back to it.
+	Pseudonymize bool
+}
+
+// ExportActivities streams the selected activities to fn in ID order and
+// returns the ID of the last one, which resumes the export through
+// ExportFilter.AfterID.
+func (s *AuditService) ExportActivities(ctx context.Context, opts ExportOptions, fn func(*domain.Activity) error) (int64, error) {
+	if opts.Pseudonymize && len(s.pseudonymKey) == 0 {
+		return 0, domain.ErrPseudonymKeyNotConfigured
+	}
+
+	lastID := opts.Filter.AfterID
+	err := s.activityRepo.ExportActivities(ctx, opts.TenantID, opts.Filter, func(activity *domain.Activity) error {
+		if opts.Pseudonymize {
+			activity.PhoneNumber = s.pseudonymize(activity.PhoneNumber)
+			if activity.Actor != "" {
+				activity.Actor = s.pseudonymize(activity.Actor)
+			}
+			activity.PrevHash = ""
+			activity.Hash = ""
+			activity.Risk = nil
+		}
+		if err := fn(activity); err != nil {
+			return err
+		}
+		lastID = activity.ID
+		return nil
+	})
+	return lastID, err
+}
+
+func (s *AuditService) pseudonymize(phoneNumber string) string {
+	mac := hmac.New(sha256.New, s.pseudonymKey)
+	mac.Write([]byte(phoneNumber))
+	return "p:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ActivityEncoder writes exported activities in one ExportFormat.
+type ActivityEncoder interface {
+	Encode(activity *domain.Activity) error
+	// Flush writes any buffered output.
+	Flush() error
+}
+
+// NewActivityEncoder returns an encoder writing the format to w.
+func NewActivityEncoder(w io.Writer, format ExportFormat) (ActivityEncoder, error) {
+	switch format {
+	case ExportJSONL:
+		return &jsonlEncoder{enc: json.NewEncoder(w)}, nil
+	case ExportCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", domain.ErrUnknownExportFormat, format)
+	}
+}
+
+// exportedActivity is the record layout shared by every export format.
+type exportedActivity struct {
+	ID            int64                  `json:"id"`
+	TenantID      string                 `json:"tenant_id"`
+	PhoneNumber   string                 `json:"phone_number"`
+	Type          string                 `json:"type"`
+	Actor         string                 `json:"actor,omitempty"`
+	Outcome       string                 `json:"outcome"`
+	FailureReason string                 `json:"failure_reason,omitempty"`
+	IP            string                 `json:"ip,omitempty"`
+	UserAgent     string                 `json:"user_agent,omitempty"`
+	DeviceID      string                 `json:"device_id,omitempty"`
+	RequestID     string                 `json:"request_id,omitempty"`
+	Metadata      map[string]string      `json:"metadata,omitempty"`
+	Risk          *domain.RiskAssessment `json:"risk,omitempty"`
+	Timestamp     string                 `json:"timestamp"`
+	Hash          string                 `json:"hash,omitempty"`
+}
+
+func newExportedActivity(a *domain.Activity) exportedActivity {
+	return exportedActivity{
+		ID:            a.ID,
+		TenantID:      a.TenantID,
+		PhoneNumber:   a.PhoneNumber,
+		Type:          string(a.Type),
+		Actor:         a.Actor,
+		Outcome:       string(a.Outcome),
+		FailureReason: a.FailureReason,
+		IP:            a.Client.IP,
+		UserAgent:     a.Client.UserAgent,
+		DeviceID:      a.Client.DeviceID,
+		RequestID:     a.Client.RequestID,
+		Metadata:      a.Metadata,
+		Risk:          a.Risk,
+		Timestamp:     a.Timestamp.UTC().Format(time.RFC3339Nano),
+		Hash:          a.Hash,
+	}
+}
+
+type jsonlEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlEncoder) Encode(activity *domain.Activity) error {
+	return e.enc.Encode(newExportedActivity(activity))
+}
+
+func (e *jsonlEncoder) Flush() error { return nil }
+
+var csvHeader = []string{"id", "tenant_id", "phone_number", "type", "actor", "outcome", "failure_reason", "ip", "user_agent", "device_id", "request_id", "metadata", "timestamp", "hash", "risk_score", "risk_decision", "risk_signals"}
+
+type csvEncoder struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (e *csvEncoder) Encode(activity *domain.Activity) error {
+	if !e.wroteHeader {
+		if err := e.w.Write(csvHeader); err != nil {
+			return err
+		}
+		e.wroteHeader = true
+	}
+
+	r := newExportedActivity(activity)
+	metadata := ""
+	if len(r.Metadata) > 0 {
+		b, err := json.Marshal(r.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(b)
+	}
+	var riskScore, riskDecision, riskSignals string
+	if r.Risk != nil {
+		riskScore = strconv.Itoa(r.Risk.Score)
+		riskDecision = string(r.Risk.Decision)
+		if len(r.Risk.Signals) > 0 {
+			b, err := json.Marshal(r.Risk.Signals)
+			if err != nil {
+				return err
+			}
+			riskSignals = string(b)
+		}
+	}
+	return e.w.Write([]string{
+		strconv.FormatInt(r.ID, 10), r.TenantID, r.PhoneNumber, r.Type, r.Actor, r.Outcome, r.FailureReason,
+		r.IP, r.UserAgent, r.DeviceID, r.RequestID, metadata, r.Timestamp, r.Hash,
+		riskScore, riskDecision, riskSignals,
+	})
+}
+
+func (e *csvEncoder) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
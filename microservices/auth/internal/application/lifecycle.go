@@ -0,0 +1,75 @@
+package application
+
+import (
+	"context"
+	eventsv1 "midaslabs/gen/events/v1"
+	"midaslabs/microservices/auth/internal/domain"
+	"midaslabs/sdk/contracts"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// UserEvents queues user lifecycle events in the outbox. The events are only
+// useful where every interested service gets its own copy, so they are
+// dropped unless enabled, rather than piling up in a queue nobody consumes.
+type UserEvents struct {
+	outbox  domain.OutboxRepository
+	enabled bool
+}
+
+func NewUserEvents(outbox domain.OutboxRepository, enabled bool) *UserEvents {
+	return &UserEvents{outbox: outbox, enabled: enabled}
+}
+
+// Publish queues a lifecycle event of the user, describing the user as it is
+// now, within the transaction in ctx if there is one. The reason and actor
+// explain the change, when there is one to explain.
+func (e *UserEvents) Publish(ctx context.Context, eventType domain.UserEventType, user *domain.User, reason, actor string) error {
+	return e.enqueue(ctx, string(eventType), &eventsv1.UserEvent{
+		TenantId:    user.TenantID,
+		PhoneNumber: user.PhoneNumber,
+		Status:      string(user.Status),
+		Verified:    user.Verified,
+		Reason:      reason,
+		Actor:       actor,
+		LastLoginAt: timestampOf(user.LastLoginAt),
+		OccurredAt:  timestamppb.Now(),
+	})
+}
+
+// PublishReverificationRequired queues a user.reverification_required event
+// for a user who was just demoted, so that the previous holder of a
+// reassigned number can step in.
+func (e *UserEvents) PublishReverificationRequired(ctx context.Context, user *domain.User, reason string) error {
+	return e.enqueue(ctx, contracts.TopicReverificationRequired, &eventsv1.ReverificationRequiredEvent{
+		TenantId:    user.TenantID,
+		PhoneNumber: user.PhoneNumber,
+		Reason:      reason,
+		LastLoginAt: timestampOf(user.LastLoginAt),
+		OccurredAt:  timestamppb.Now(),
+	})
+}
+
+func (e *UserEvents) enqueue(ctx context.Context, topic string, event proto.Message) error {
+	if !e.enabled {
+		return nil
+	}
+	message, err := contracts.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return e.outbox.EnqueueMessage(ctx, topic, message)
+}
+
+func (s *AuthService) publishUserEvent(ctx context.Context, eventType domain.UserEventType, user *domain.User, reason, actor string) error {
+	return s.userEvents.Publish(ctx, eventType, user, reason, actor)
+}
+
+func timestampOf(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,37 @@
+package application
+
+import (
+	"context"
+	"midaslabs/microservices/auth/internal/domain"
+	"midaslabs/sdk/contracts"
+	"slices"
+	"testing"
+)
+
+func TestUserEventsFollowEnabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		topics  []string
+	}{
+		{name: "enabled", enabled: true, topics: []string{string(domain.UserUpdated), contracts.TopicReverificationRequired}},
+		{name: "disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &fakeOutbox{}
+			events := NewUserEvents(outbox, tt.enabled)
+			user := domain.NewUser("acme", "+15550100")
+
+			if err := events.Publish(context.Background(), domain.UserUpdated, user, "dormant", ""); err != nil {
+				t.Fatal(err)
+			}
+			if err := events.PublishReverificationRequired(context.Background(), user, "dormant"); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(outbox.topics, tt.topics) {
+				t.Errorf("queued %v, want %v", outbox.topics, tt.topics)
+			}
+		})
+	}
+}
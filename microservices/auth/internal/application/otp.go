@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	eventsv1 "midaslabs/gen/events/v1"
+	"midaslabs/microservices/auth/internal/domain"
+	"midaslabs/sdk/contracts"
+	"time"
+)
+
+// otpTopic carries OTPs to the OTP service.
+const otpTopic = contracts.TopicVerification
+
+// Results of an attempt recorded in the OTP ledger.
+const (
+	otpAttemptMatched = "matched"
+	otpAttemptInvalid = "invalid"
+	otpAttemptExpired = "expired"
+)
+
+const (
+	defaultOTPTimelineWindow = 7 * 24 * time.Hour
+	maxOTPTimelineEvents     = 500
+)
+
+// checkOTP loads the phone number's OTP and checks the submitted code against
+// it, recording the attempt in the OTP ledger.
+func (s *AuthService) checkOTP(ctx context.Context, tenant *domain.Tenant, phoneNumber, code string) (*domain.OTP, error) {
+	// Retrieve OTP from the database
+	storedOTP, err := s.otpRepo.GetOTP(ctx, tenant.ID, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if OTP is expired
+	if time.Now().After(storedOTP.Expiration) {
+		s.recordOTPEvent(ctx, storedOTP, domain.OTPEventAttempted, otpAttemptExpired)
+		return nil, domain.ErrOTPExpired
+	}
+
+	// Check if OTP matches
+	if storedOTP.Code != code {
+		s.recordOTPEvent(ctx, storedOTP, domain.OTPEventAttempted, otpAttemptInvalid)
+		return nil, domain.ErrInvalidOTP
+	}
+
+	s.recordOTPEvent(ctx, storedOTP, domain.OTPEventAttempted, otpAttemptMatched)
+	return storedOTP, nil
+}
+
+// recordOTPEvent appends an event to the OTP ledger. The ledger only serves
+// diagnosis, so failing to write it never fails the flow. It must not be
+// called within a transaction, where a failed write would abort the
+// transaction; use appendOTPEvent there.
+func (s *AuthService) recordOTPEvent(ctx context.Context, otp *domain.OTP, eventType domain.OTPEventType, detail string) {
+	_ = s.appendOTPEvent(ctx, otp, eventType, detail)
+}
+
+// appendOTPEvent appends an event to the OTP ledger. OTPs stored before the
+// ledger existed have no ID and are skipped.
+func (s *AuthService) appendOTPEvent(ctx context.Context, otp *domain.OTP, eventType domain.OTPEventType, detail string) error {
+	if otp.ID == "" {
+		return nil
+	}
+
+	// Log the OTP event
+	event := &domain.OTPEvent{
+		TenantID:    otp.TenantID,
+		OTPID:       otp.ID,
+		PhoneNumber: otp.PhoneNumber,
+		Type:        eventType,
+		Detail:      detail,
+		Source:      domain.OTPEventSourceAuth,
+		Timestamp:   time.Now(),
+	}
+	return s.otpEventRepo.RecordOTPEvent(ctx, event)
+}
+
+// HandleOTPDelivery records a delivery report of the OTP service in the OTP
+// ledger. It is subscribed to the otp.delivery topic.
+func (s *AuthService) HandleOTPDelivery(ctx context.Context, message []byte) error {
+	var report eventsv1.OTPDeliveryEvent
+	if err := contracts.Unmarshal(ctx, message, &report); err != nil {
+		return err
+	}
+
+	event := &domain.OTPEvent{
+		TenantID:    report.TenantId,
+		OTPID:       report.OtpId,
+		PhoneNumber: report.PhoneNumber,
+		Type:        domain.OTPEventDelivered,
+		Source:      domain.OTPEventSourceOTP,
+		Timestamp:   time.Now(),
+	}
+	if !report.Delivered {
+		event.Type = domain.OTPEventDeliveryFailed
+		event.Detail = report.Error
+	}
+	if report.OccurredAt != nil {
+		event.Timestamp = report.OccurredAt.AsTime()
+	}
+	return s.otpEventRepo.RecordOTPEvent(ctx, event)
+}
+
+// ObserveOutboxPublish records the publishing of OTP messages by the outbox
+// relay in the OTP ledger.
+func (s *AuthService) ObserveOutboxPublish(ctx context.Context, message *domain.OutboxMessage, err error) {
+	if message.Topic != otpTopic {
+		return
+	}
+	// The outbox queues the default wire format
+	var event eventsv1.OTPVerificationEvent
+	if decodeErr := contracts.JSON.Unmarshal(message.Payload, &event); decodeErr != nil {
+		return
+	}
+
+	otp := &domain.OTP{ID: event.OtpId, TenantID: event.TenantId, PhoneNumber: event.PhoneNumber}
+	if errors.Is(err, domain.ErrOutboxMessageExpired) {
+		s.recordOTPEvent(ctx, otp, domain.OTPEventExpired, "expired before publishing")
+		return
+	}
+	if err != nil {
+		s.recordOTPEvent(ctx, otp, domain.OTPEventPublishFailed, err.Error())
+		return
+	}
+	s.recordOTPEvent(ctx, otp, domain.OTPEventPublished, "")
+}
+
+// GetOTPTimeline returns the OTP ledger of a phone number since the given
+// time, oldest first. It defaults to the last week.
+func (s *AuthService) GetOTPTimeline(ctx context.Context, phoneNumber string, since time.Time) ([]*domain.OTPEvent, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if since.IsZero() {
+		since = time.Now().Add(-defaultOTPTimelineWindow)
+	}
+	return s.otpEventRepo.ListOTPEvents(ctx, tenant.ID, phoneNumber, since, maxOTPTimelineEvents)
+}
+
+func generateOTPID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
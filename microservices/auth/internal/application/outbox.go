@@ -0,0 +1,170 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"midaslabs/microservices/auth/internal/domain"
+	"midaslabs/sdk/contracts"
+	"midaslabs/sdk/envelope"
+	"time"
+)
+
+const (
+	outboxRetryBase = time.Second
+	outboxRetryMax  = 5 * time.Minute
+	// outboxPublishTimeout bounds the wait for the broker to confirm a message.
+	outboxPublishTimeout = 10 * time.Second
+	// outboxClaimLease is how long a claimed batch is reserved for the relay
+	// that claimed it.
+	outboxClaimLease = time.Minute
+
+	defaultOutboxMaxAttempts = 20
+)
+
+// outboxStats counts the messages of every relay in the process. It is
+// published once, as expvar panics on duplicate names.
+var outboxStats = expvar.NewMap("outbox")
+
+// OutboxRelay publishes the messages of the outbox to the message broker and
+// marks them sent. Delivery is at least once: a message is published again
+// if marking it sent fails. Instances relay concurrently without publishing
+// the same message twice at a time. Messages that fail maxAttempts times are
+// parked as dead.
+type OutboxRelay struct {
+	outbox      domain.OutboxRepository
+	broker      contracts.MessageBroker
+	batchSize   int
+	maxAttempts int
+	observe     func(ctx context.Context, message *domain.OutboxMessage, err error)
+}
+
+// NewOutboxRelay creates an OutboxRelay. When observe is set, it is told the
+// outcome of every publish once the outcome is committed. Counts are
+// published through expvar under "outbox".
+func NewOutboxRelay(outbox domain.OutboxRepository, broker contracts.MessageBroker, batchSize, maxAttempts int, observe func(ctx context.Context, message *domain.OutboxMessage, err error)) *OutboxRelay {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultOutboxMaxAttempts
+	}
+	return &OutboxRelay{
+		outbox:      outbox,
+		broker:      broker,
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+		observe:     observe,
+	}
+}
+
+// Run relays pending messages every interval until ctx is cancelled. Full
+// batches are followed immediately by the next one. Failed runs are reported
+// to onError and retried on the next interval.
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration, onError func(err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := r.relayBatch(ctx)
+			if err != nil {
+				outboxStats.Add("errors", 1)
+				onError(err)
+				break
+			}
+			if n < r.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayBatch publishes one batch of due messages and returns how many it
+// settled. The batch is leased rather than locked, so no transaction is open
+// while the broker is waited on.
+func (r *OutboxRelay) relayBatch(ctx context.Context) (int, error) {
+	claimed := time.Now()
+	messages, err := r.outbox.ClaimMessages(ctx, r.batchSize, claimed.Add(outboxClaimLease))
+	if err != nil {
+		return 0, err
+	}
+
+	for i, message := range messages {
+		// Leave the rest to be claimed again rather than publish them after
+		// the lease has run out
+		if time.Since(claimed) > outboxClaimLease-outboxPublishTimeout {
+			return i, nil
+		}
+
+		publishErr := r.publish(ctx, message)
+		dead, err := r.settle(ctx, message, publishErr)
+		if err != nil {
+			return i, err
+		}
+
+		switch {
+		case errors.Is(publishErr, domain.ErrOutboxMessageExpired):
+			outboxStats.Add("expired", 1)
+		case dead:
+			outboxStats.Add("dead", 1)
+		case publishErr != nil:
+			outboxStats.Add("failed", 1)
+		default:
+			outboxStats.Add("published", 1)
+		}
+		if r.observe != nil {
+			r.observe(ctx, message, publishErr)
+		}
+	}
+	return len(messages), nil
+}
+
+// settle records the outcome of publishing a message and reports whether it
+// was parked as dead.
+func (r *OutboxRelay) settle(ctx context.Context, message *domain.OutboxMessage, publishErr error) (bool, error) {
+	switch {
+	case publishErr == nil || errors.Is(publishErr, domain.ErrOutboxMessageExpired):
+		// Expired messages are done with too
+		return false, r.outbox.MarkMessageSent(ctx, message.ID)
+	case message.Attempts+1 >= r.maxAttempts:
+		return true, r.outbox.MarkMessageDead(ctx, message.ID, publishErr.Error())
+	default:
+		retryAt := time.Now().Add(outboxRetryDelay(message.Attempts + 1))
+		return false, r.outbox.MarkMessageFailed(ctx, message.ID, publishErr.Error(), retryAt)
+	}
+}
+
+// publish publishes a message unless it has expired, in which case it
+// returns ErrOutboxMessageExpired.
+func (r *OutboxRelay) publish(ctx context.Context, message *domain.OutboxMessage) error {
+	meta := envelope.Metadata{
+		// Republishing after a lost confirm keeps the event's ID and time
+		ID:   fmt.Sprintf("outbox-%d", message.ID),
+		Time: message.CreatedAt,
+	}
+	if message.ExpiresAt != nil {
+		if !time.Now().Before(*message.ExpiresAt) {
+			return domain.ErrOutboxMessageExpired
+		}
+		meta.Expiry = *message.ExpiresAt
+	}
+
+	ctx, cancel := context.WithTimeout(envelope.WithPublishMetadata(ctx, meta), outboxPublishTimeout)
+	defer cancel()
+	return r.broker.Publish(ctx, message.Topic, message.Payload)
+}
+
+// outboxRetryDelay backs off exponentially with the number of failed
+// attempts, up to outboxRetryMax.
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < outboxRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxRetryMax)
+}
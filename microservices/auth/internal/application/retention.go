@@ -0,0 +1,173 @@
+package application
+
+import (
+	"context"
+	"expvar"
+	"midaslabs/microservices/auth/internal/domain"
+	"time"
+)
+
+// RetentionPolicies configures how long rows are kept. A zero duration
+// disables the corresponding job.
+type RetentionPolicies struct {
+	// ExpiredOTPs is how long an OTP is kept after it expires.
+	ExpiredOTPs time.Duration
+	// UnverifiedUsers is how long a signup may stay unverified.
+	UnverifiedUsers time.Duration
+	// Activities is how long activities are kept.
+	Activities time.Duration
+	// OTPEvents is how long the OTP ledger is kept.
+	OTPEvents time.Duration
+	// SentOutboxMessages is how long relayed outbox messages are kept.
+	SentOutboxMessages time.Duration
+}
+
+// retentionStats is published once per process, as expvar panics on
+// duplicate names.
+var retentionStats = expvar.NewMap("retention")
+
+const defaultRetentionBatchSize = 1000
+
+type retentionJob struct {
+	name   string
+	maxAge time.Duration
+	purge  func(ctx context.Context, before time.Time, limit int) (int64, error)
+	stats  *expvar.Map
+}
+
+// RetentionService periodically purges rows past their retention period.
+// Each job runs on at most one instance at a time and deletes in batches.
+// Progress and counts are published through expvar under "retention".
+type RetentionService struct {
+	leaderLock domain.LeaderLock
+	batchSize  int
+	jobs       []*retentionJob
+}
+
+func NewRetentionService(repo domain.RetentionRepository, leaderLock domain.LeaderLock, transactor domain.Transactor, userEvents *UserEvents, policies RetentionPolicies, batchSize int) *RetentionService {
+	if batchSize <= 0 {
+		batchSize = defaultRetentionBatchSize
+	}
+	s := &RetentionService{
+		leaderLock: leaderLock,
+		batchSize:  batchSize,
+	}
+
+	for _, job := range []*retentionJob{
+		{name: "otps", maxAge: policies.ExpiredOTPs, purge: repo.DeleteExpiredOTPs},
+		{name: "unverified_users", maxAge: policies.UnverifiedUsers, purge: purgeUnverifiedUsers(repo, transactor, userEvents)},
+		{name: "activities", maxAge: policies.Activities, purge: repo.DeleteActivities},
+		{name: "otp_events", maxAge: policies.OTPEvents, purge: repo.DeleteOTPEvents},
+		{name: "outbox", maxAge: policies.SentOutboxMessages, purge: repo.DeleteSentOutboxMessages},
+	} {
+		if job.maxAge <= 0 {
+			continue
+		}
+		job.stats = new(expvar.Map).Init()
+		retentionStats.Set(job.name, job.stats)
+		s.jobs = append(s.jobs, job)
+	}
+	return s
+}
+
+// purgeUnverifiedUsers queues a user.deleted event for every purged user in
+// the transaction that deletes it.
+func purgeUnverifiedUsers(repo domain.RetentionRepository, transactor domain.Transactor, userEvents *UserEvents) func(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return func(ctx context.Context, before time.Time, limit int) (int64, error) {
+		var n int64
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			users, err := repo.DeleteUnverifiedUsers(ctx, before, limit)
+			if err != nil {
+				return err
+			}
+			for _, user := range users {
+				if err := userEvents.Publish(ctx, domain.UserDeleted, user, "unverified", ""); err != nil {
+					return err
+				}
+			}
+			n = int64(len(users))
+			return nil
+		})
+		return n, err
+	}
+}
+
+// Run runs every job each interval until ctx is cancelled. Failed runs are
+// reported to onError and retried on the next interval. A zero interval
+// disables the jobs.
+func (s *RetentionService) Run(ctx context.Context, interval time.Duration, onError func(job string, err error)) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, job := range s.jobs {
+			if err := s.runJob(ctx, job); err != nil {
+				onError(job.name, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *RetentionService) runJob(ctx context.Context, job *retentionJob) error {
+	unlock, acquired, err := s.leaderLock.TryLock(ctx, "retention:"+job.name)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		// Another instance is running this job
+		job.stats.Add("skipped", 1)
+		return nil
+	}
+	defer unlock()
+
+	started := time.Now()
+	before := started.Add(-job.maxAge)
+	job.stats.Add("runs", 1)
+	job.stats.Set("running", expvarInt(1))
+	job.stats.Set("last_started_at", expvarString(started.Format(time.RFC3339)))
+	job.stats.Set("last_run_deleted", expvarInt(0))
+	defer job.stats.Set("running", expvarInt(0))
+
+	var deleted int64
+	for ctx.Err() == nil {
+		n, err := job.purge(ctx, before, s.batchSize)
+		if err != nil {
+			job.stats.Add("errors", 1)
+			job.stats.Set("last_error", expvarString(err.Error()))
+			return err
+		}
+		deleted += n
+		job.stats.Add("deleted_total", n)
+		job.stats.Add("batches", 1)
+		job.stats.Set("last_run_deleted", expvarInt(deleted))
+
+		if n < int64(s.batchSize) {
+			break
+		}
+	}
+
+	job.stats.Set("last_finished_at", expvarString(time.Now().Format(time.RFC3339)))
+	job.stats.Set("last_duration_ms", expvarInt(time.Since(started).Milliseconds()))
+	return ctx.Err()
+}
+
+func expvarInt(v int64) *expvar.Int {
+	i := new(expvar.Int)
+	i.Set(v)
+	return i
+}
+
+func expvarString(v string) *expvar.String {
+	s := new(expvar.String)
+	s.Set(v)
+	return s
+}
@@ -0,0 +1,264 @@
+package application
+
+import (
+	"context"
+	"errors"
+	eventsv1 "midaslabs/gen/events/v1"
+	"midaslabs/microservices/auth/internal/domain"
+	"midaslabs/sdk/contracts"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeRetentionRepo holds a number of purgeable rows per table and records
+// the limit of every purge.
+type fakeRetentionRepo struct {
+	rows    map[string]int64
+	limits  map[string][]int
+	befores map[string]time.Time
+	err     error
+}
+
+func newFakeRetentionRepo(rows map[string]int64) *fakeRetentionRepo {
+	return &fakeRetentionRepo{rows: rows, limits: map[string][]int{}, befores: map[string]time.Time{}}
+}
+
+func (r *fakeRetentionRepo) purge(table string, before time.Time, limit int) (int64, error) {
+	r.limits[table] = append(r.limits[table], limit)
+	r.befores[table] = before
+	if r.err != nil {
+		return 0, r.err
+	}
+	n := min(r.rows[table], int64(limit))
+	r.rows[table] -= n
+	return n, nil
+}
+
+func (r *fakeRetentionRepo) DeleteExpiredOTPs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.purge("otps", before, limit)
+}
+
+func (r *fakeRetentionRepo) DeleteUnverifiedUsers(ctx context.Context, before time.Time, limit int) ([]*domain.User, error) {
+	n, err := r.purge("unverified_users", before, limit)
+	users := make([]*domain.User, n)
+	for i := range users {
+		users[i] = &domain.User{TenantID: "acme", PhoneNumber: "+15550100", Status: domain.UserStatusActive}
+	}
+	return users, err
+}
+
+func (r *fakeRetentionRepo) DeleteActivities(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.purge("activities", before, limit)
+}
+
+func (r *fakeRetentionRepo) DeleteOTPEvents(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.purge("otp_events", before, limit)
+}
+
+func (r *fakeRetentionRepo) DeleteSentOutboxMessages(ctx context.Context, before time.Time, limit int) (int64, error) {
+	return r.purge("outbox", before, limit)
+}
+
+// fakeTransactor runs fn without a transaction.
+type fakeTransactor struct{}
+
+func (fakeTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// fakeOutbox records the topics and payloads of queued messages.
+type fakeOutbox struct {
+	domain.OutboxRepository
+	topics   []string
+	payloads [][]byte
+}
+
+func (o *fakeOutbox) EnqueueMessage(ctx context.Context, topic string, payload []byte) error {
+	o.topics = append(o.topics, topic)
+	o.payloads = append(o.payloads, payload)
+	return nil
+}
+
+type fakeLeaderLock struct {
+	held     bool
+	released int
+}
+
+func (l *fakeLeaderLock) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	if l.held {
+		return nil, false, nil
+	}
+	return func() { l.released++ }, true, nil
+}
+
+func TestRetentionPurgesInBatches(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      int64
+		batchSize int
+		limits    []int
+	}{
+		{name: "nothing to purge", rows: 0, batchSize: 100, limits: []int{100}},
+		{name: "partial batch", rows: 40, batchSize: 100, limits: []int{100}},
+		{name: "several batches", rows: 250, batchSize: 100, limits: []int{100, 100, 100}},
+		{name: "exact multiple", rows: 200, batchSize: 100, limits: []int{100, 100, 100}},
+		{name: "default batch size", rows: 1500, batchSize: 0, limits: []int{defaultRetentionBatchSize, defaultRetentionBatchSize}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRetentionRepo(map[string]int64{"otps": tt.rows})
+			lock := &fakeLeaderLock{}
+			s := NewRetentionService(repo, lock, nil, nil, RetentionPolicies{ExpiredOTPs: time.Hour}, tt.batchSize)
+
+			if err := s.runJob(context.Background(), s.jobs[0]); err != nil {
+				t.Fatal(err)
+			}
+			if got := repo.limits["otps"]; !slices.Equal(got, tt.limits) {
+				t.Errorf("purge limits = %v, want %v", got, tt.limits)
+			}
+			if repo.rows["otps"] != 0 {
+				t.Errorf("%d rows left", repo.rows["otps"])
+			}
+			if got := s.jobs[0].stats.Get("last_run_deleted").String(); got != strconv.FormatInt(tt.rows, 10) {
+				t.Errorf("last_run_deleted = %s, want %d", got, tt.rows)
+			}
+			if lock.released != 1 {
+				t.Errorf("lock released %d times, want 1", lock.released)
+			}
+		})
+	}
+}
+
+func TestRetentionPurgesBeforeMaxAge(t *testing.T) {
+	repo := newFakeRetentionRepo(map[string]int64{})
+	s := NewRetentionService(repo, &fakeLeaderLock{}, nil, nil, RetentionPolicies{Activities: 24 * time.Hour}, 10)
+
+	started := time.Now()
+	if err := s.runJob(context.Background(), s.jobs[0]); err != nil {
+		t.Fatal(err)
+	}
+	before := repo.befores["activities"]
+	if want := started.Add(-24 * time.Hour); before.Before(want.Add(-time.Second)) || before.After(want.Add(time.Second)) {
+		t.Errorf("purged before %v, want about %v", before, want)
+	}
+}
+
+func TestRetentionQueuesDeletedUsers(t *testing.T) {
+	repo := newFakeRetentionRepo(map[string]int64{"unverified_users": 3})
+	outbox := &fakeOutbox{}
+	s := NewRetentionService(repo, &fakeLeaderLock{}, fakeTransactor{}, NewUserEvents(outbox, true), RetentionPolicies{UnverifiedUsers: time.Hour}, 10)
+
+	if err := s.runJob(context.Background(), s.jobs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(outbox.topics) != 3 {
+		t.Fatalf("queued %d events, want 3", len(outbox.topics))
+	}
+	for i, topic := range outbox.topics {
+		if topic != string(domain.UserDeleted) {
+			t.Errorf("topic = %s, want %s", topic, domain.UserDeleted)
+		}
+		var event eventsv1.UserEvent
+		if err := contracts.Unmarshal(context.Background(), outbox.payloads[i], &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.TenantId != "acme" || event.Reason != "unverified" || event.Status != string(domain.UserStatusActive) {
+			t.Errorf("event = %v", &event)
+		}
+	}
+	if got := s.jobs[0].stats.Get("last_run_deleted").String(); got != "3" {
+		t.Errorf("last_run_deleted = %s, want 3", got)
+	}
+}
+
+func TestRetentionDropsDisabledUserEvents(t *testing.T) {
+	repo := newFakeRetentionRepo(map[string]int64{"unverified_users": 3})
+	outbox := &fakeOutbox{}
+	s := NewRetentionService(repo, &fakeLeaderLock{}, fakeTransactor{}, NewUserEvents(outbox, false), RetentionPolicies{UnverifiedUsers: time.Hour}, 10)
+
+	if err := s.runJob(context.Background(), s.jobs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(outbox.topics) != 0 {
+		t.Errorf("queued %d events without a destination for them", len(outbox.topics))
+	}
+	if repo.rows["unverified_users"] != 0 {
+		t.Errorf("%d users left", repo.rows["unverified_users"])
+	}
+}
+
+func TestRetentionSkipsDisabledJobs(t *testing.T) {
+	repo := newFakeRetentionRepo(map[string]int64{"otps": 5, "outbox": 5})
+	s := NewRetentionService(repo, &fakeLeaderLock{}, nil, nil, RetentionPolicies{SentOutboxMessages: time.Hour}, 10)
+
+	if len(s.jobs) != 1 || s.jobs[0].name != "outbox" {
+		t.Fatalf("jobs = %v, want only outbox", s.jobs)
+	}
+}
+
+func TestRetentionSkipsWhenLockHeld(t *testing.T) {
+	repo := newFakeRetentionRepo(map[string]int64{"otps": 5})
+	s := NewRetentionService(repo, &fakeLeaderLock{held: true}, nil, nil, RetentionPolicies{ExpiredOTPs: time.Hour}, 10)
+
+	if err := s.runJob(context.Background(), s.jobs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.limits["otps"]) != 0 {
+		t.Errorf("purged %d batches while another instance held the lock", len(repo.limits["otps"]))
+	}
+	if got := s.jobs[0].stats.Get("skipped").String(); got != "1" {
+		t.Errorf("skipped = %s, want 1", got)
+	}
+}
+
+func TestRetentionStopsOnError(t *testing.T) {
+	repo := newFakeRetentionRepo(map[string]int64{"otps": 500})
+	repo.err = errors.New("connection reset")
+	s := NewRetentionService(repo, &fakeLeaderLock{}, nil, nil, RetentionPolicies{ExpiredOTPs: time.Hour}, 100)
+
+	if err := s.runJob(context.Background(), s.jobs[0]); !errors.Is(err, repo.err) {
+		t.Fatalf("err = %v, want %v", err, repo.err)
+	}
+	if len(repo.limits["otps"]) != 1 {
+		t.Errorf("purged %d batches after an error, want 1", len(repo.limits["otps"]))
+	}
+}
+
+func TestRetentionRunWithoutInterval(t *testing.T) {
+	repo := newFakeRetentionRepo(map[string]int64{"otps": 5})
+	s := NewRetentionService(repo, &fakeLeaderLock{}, nil, nil, RetentionPolicies{ExpiredOTPs: time.Hour}, 10)
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(context.Background(), 0, func(job string, err error) { t.Error(job, err) })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return for a zero interval")
+	}
+	if len(repo.limits["otps"]) != 0 {
+		t.Error("jobs ran although the interval disables them")
+	}
+}
+
+func TestRetentionRunStopsOnCancel(t *testing.T) {
+	repo := newFakeRetentionRepo(map[string]int64{"otps": 5})
+	s := NewRetentionService(repo, &fakeLeaderLock{}, nil, nil, RetentionPolicies{ExpiredOTPs: time.Hour}, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, time.Hour, func(job string, err error) {})
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
@@ -0,0 +1,121 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"midaslabs/microservices/auth/internal/domain"
+	"strings"
+	"time"
+)
+
+const recoveryCodeCount = 10
+
+// requireReverification demotes an account, records it and notifies
+// interested services.
+func (s *AuthService) requireReverification(ctx context.Context, user *domain.User, reason string) error {
+	return s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		user.RequireReverification()
+		if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+			return err
+		}
+		if err := s.publishUserEvent(ctx, domain.UserUpdated, user, reason, ""); err != nil {
+			return err
+		}
+
+		// Log the demotion activity
+		activity := &domain.Activity{
+			TenantID:    user.TenantID,
+			PhoneNumber: user.PhoneNumber,
+			Type:        domain.ActivityReverificationRequired,
+			Metadata:    map[string]string{"reason": reason},
+			Timestamp:   time.Now(),
+		}
+		if err := s.recordActivity(ctx, activity); err != nil {
+			return err
+		}
+
+		return s.userEvents.PublishReverificationRequired(ctx, user, reason)
+	})
+}
+
+// reverifyWithoutCode decides whether a demoted user who sent no recovery code
+// may reverify with the SMS OTP alone. That is only allowed for users without
+// codes, and only once the tenant's cooling-off period has passed since the
+// demotion, which gives the previous holder of a reassigned number time to
+// notice the reverification_required event and step in.
+func (s *AuthService) reverifyWithoutCode(ctx context.Context, tenant *domain.Tenant, user *domain.User) error {
+	hasCodes, err := s.recoveryRepo.HasRecoveryCodes(ctx, tenant.ID, user.PhoneNumber)
+	if err != nil {
+		return err
+	}
+	if hasCodes {
+		return domain.ErrReverificationRequired
+	}
+	if !user.ReverificationCooledOff(tenant.ReverificationCoolingOff, time.Now()) {
+		return domain.ErrReverificationPending
+	}
+	return nil
+}
+
+// GenerateRecoveryCodes replaces the user's recovery codes with a fresh set
+// and returns them. The codes are only ever shown this once.
+func (s *AuthService) GenerateRecoveryCodes(ctx context.Context, phoneNumber string) ([]string, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsVerified() {
+		return nil, domain.ErrUserNotVerified
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	if err := s.recoveryRepo.ReplaceRecoveryCodes(ctx, tenant.ID, phoneNumber, hashes); err != nil {
+		return nil, err
+	}
+
+	// Log the recovery code generation activity
+	activity := &domain.Activity{
+		TenantID:    tenant.ID,
+		PhoneNumber: phoneNumber,
+		Type:        domain.ActivityRecoveryCodes,
+		Timestamp:   time.Now(),
+	}
+	if err := s.recordActivity(ctx, activity); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 5) // 5 bytes encode to 8 base32 characters
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := base32.StdEncoding.EncodeToString(b)
+	return code[:4] + "-" + code[4:], nil
+}
+
+// hashRecoveryCode normalises a recovery code as typed by the user and
+// returns its SHA-256 digest.
+func hashRecoveryCode(code string) string {
+	normalised := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalised))
+	return hex.EncodeToString(sum[:])
+}
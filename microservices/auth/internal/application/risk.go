@@ -0,0 +1,224 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"midaslabs/microservices/auth/internal/domain"
+	"strings"
+	"time"
+)
+
+// RiskThresholds maps risk scores to decisions. Scores at or above
+// Challenge require a second factor; scores at or above Deny are refused.
+type RiskThresholds struct {
+	Challenge int
+	Deny      int
+}
+
+// StepUpFallback decides the challenged logins of users without a second
+// factor to step up with.
+type StepUpFallback string
+
+const (
+	// StepUpFallbackDeny refuses the login.
+	StepUpFallbackDeny StepUpFallback = "deny"
+	// StepUpFallbackAllow completes the login with the SMS OTP alone.
+	StepUpFallbackAllow StepUpFallback = "allow"
+)
+
+// RuleRiskEngine scores a login attempt as the sum of the signals raised by
+// its rules, capped at 100.
+type RuleRiskEngine struct {
+	thresholds RiskThresholds
+	rules      []domain.RiskRule
+}
+
+func NewRuleRiskEngine(thresholds RiskThresholds, rules ...domain.RiskRule) *RuleRiskEngine {
+	return &RuleRiskEngine{thresholds: thresholds, rules: rules}
+}
+
+func (e *RuleRiskEngine) Assess(ctx context.Context, attempt *domain.LoginAttempt) (*domain.RiskAssessment, error) {
+	assessment := &domain.RiskAssessment{Decision: domain.RiskAllow}
+	for _, rule := range e.rules {
+		signal, err := rule.Evaluate(ctx, attempt)
+		if err != nil {
+			return nil, err
+		}
+		if signal == nil {
+			continue
+		}
+		assessment.Signals = append(assessment.Signals, *signal)
+		assessment.Score += signal.Score
+	}
+	if assessment.Score > 100 {
+		assessment.Score = 100
+	}
+
+	switch {
+	case e.thresholds.Deny > 0 && assessment.Score >= e.thresholds.Deny:
+		assessment.Decision = domain.RiskDeny
+	case e.thresholds.Challenge > 0 && assessment.Score >= e.thresholds.Challenge:
+		assessment.Decision = domain.RiskChallenge
+	}
+	return assessment, nil
+}
+
+// IPVelocityRule flags an IP address that attempted more than Limit logins,
+// across all users of the tenant, within Window.
+type IPVelocityRule struct {
+	ActivityRepo domain.ActivityRepository
+	Window       time.Duration
+	Limit        int
+	Score        int
+}
+
+func (r *IPVelocityRule) Evaluate(ctx context.Context, attempt *domain.LoginAttempt) (*domain.RiskSignal, error) {
+	if attempt.Client.IP == "" {
+		return nil, nil
+	}
+	count, err := r.ActivityRepo.CountActivities(ctx, attempt.Tenant.ID, domain.ActivityFilter{
+		IP:    attempt.Client.IP,
+		Types: []domain.ActivityType{domain.ActivityLogin},
+		Since: attempt.Time.Add(-r.Window),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count <= r.Limit {
+		return nil, nil
+	}
+	return &domain.RiskSignal{
+		Name:   "ip_velocity",
+		Score:  r.Score,
+		Detail: fmt.Sprintf("%d login attempts from %s in %s", count, attempt.Client.IP, r.Window),
+	}, nil
+}
+
+// FailedAttemptsRule flags a user with more than Limit failed verifications
+// or logins within Window.
+type FailedAttemptsRule struct {
+	ActivityRepo domain.ActivityRepository
+	Window       time.Duration
+	Limit        int
+	Score        int
+}
+
+func (r *FailedAttemptsRule) Evaluate(ctx context.Context, attempt *domain.LoginAttempt) (*domain.RiskSignal, error) {
+	count, err := r.ActivityRepo.CountActivities(ctx, attempt.Tenant.ID, domain.ActivityFilter{
+		PhoneNumber: attempt.User.PhoneNumber,
+		Types:       []domain.ActivityType{domain.ActivityLogin, domain.ActivityVerify},
+		Outcome:     domain.OutcomeFailure,
+		Since:       attempt.Time.Add(-r.Window),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count <= r.Limit {
+		return nil, nil
+	}
+	return &domain.RiskSignal{
+		Name:   "failed_attempts",
+		Score:  r.Score,
+		Detail: fmt.Sprintf("%d failed attempts in %s", count, r.Window),
+	}, nil
+}
+
+// NewDeviceRule flags a login from a device the user has not logged in
+// from before. Users without any known clients, such as those who never
+// logged in, are not flagged.
+type NewDeviceRule struct {
+	ClientRepo domain.KnownClientRepository
+	Score      int
+}
+
+func (r *NewDeviceRule) Evaluate(ctx context.Context, attempt *domain.LoginAttempt) (*domain.RiskSignal, error) {
+	if attempt.Client.DeviceID == "" || attempt.User.LastLoginAt == nil {
+		return nil, nil
+	}
+	if tracked, err := r.ClientRepo.HasKnownClients(ctx, attempt.Tenant.ID, attempt.User.PhoneNumber); err != nil || !tracked {
+		return nil, err
+	}
+	known, err := r.ClientRepo.IsKnownDevice(ctx, attempt.Tenant.ID, attempt.User.PhoneNumber, attempt.Client.DeviceID)
+	if err != nil || known {
+		return nil, err
+	}
+	return &domain.RiskSignal{Name: "new_device", Score: r.Score, Detail: domain.DescribeUserAgent(attempt.Client.UserAgent)}, nil
+}
+
+// NewNetworkRule flags a login from an IP range the user has not logged in
+// from before. Like NewDeviceRule, it skips users without known clients.
+type NewNetworkRule struct {
+	ClientRepo domain.KnownClientRepository
+	Score      int
+}
+
+func (r *NewNetworkRule) Evaluate(ctx context.Context, attempt *domain.LoginAttempt) (*domain.RiskSignal, error) {
+	network := domain.NetworkOf(attempt.Client.IP)
+	if network == "" || attempt.User.LastLoginAt == nil {
+		return nil, nil
+	}
+	if tracked, err := r.ClientRepo.HasKnownClients(ctx, attempt.Tenant.ID, attempt.User.PhoneNumber); err != nil || !tracked {
+		return nil, err
+	}
+	known, err := r.ClientRepo.IsKnownNetwork(ctx, attempt.Tenant.ID, attempt.User.PhoneNumber, network)
+	if err != nil || known {
+		return nil, err
+	}
+	return &domain.RiskSignal{Name: "new_network", Score: r.Score, Detail: network}, nil
+}
+
+// NumberPrefixRule flags phone numbers starting with one of Prefixes, such as
+// ranges known for SMS pumping.
+type NumberPrefixRule struct {
+	Prefixes []string
+	Score    int
+}
+
+func (r *NumberPrefixRule) Evaluate(ctx context.Context, attempt *domain.LoginAttempt) (*domain.RiskSignal, error) {
+	for _, prefix := range r.Prefixes {
+		if prefix != "" && strings.HasPrefix(attempt.User.PhoneNumber, prefix) {
+			return &domain.RiskSignal{Name: "number_prefix", Score: r.Score, Detail: prefix}, nil
+		}
+	}
+	return nil, nil
+}
+
+// InactivityRule flags users who have not logged in for longer than After.
+type InactivityRule struct {
+	After time.Duration
+	Score int
+}
+
+func (r *InactivityRule) Evaluate(ctx context.Context, attempt *domain.LoginAttempt) (*domain.RiskSignal, error) {
+	if attempt.User.LastLoginAt == nil {
+		return nil, nil
+	}
+	idle := attempt.Time.Sub(*attempt.User.LastLoginAt)
+	if idle <= r.After {
+		return nil, nil
+	}
+	return &domain.RiskSignal{
+		Name:   "inactivity",
+		Score:  r.Score,
+		Detail: fmt.Sprintf("last login %d days ago", int(idle.Hours()/24)),
+	}, nil
+}
+
+// assessLogin consults the risk engine about a login step. Denied attempts
+// return ErrLoginDenied along with the assessment, so it can be recorded.
+func (s *AuthService) assessLogin(ctx context.Context, stage domain.LoginStage, tenant *domain.Tenant, user *domain.User) (*domain.RiskAssessment, error) {
+	assessment, err := s.riskEngine.Assess(ctx, &domain.LoginAttempt{
+		Stage:  stage,
+		Tenant: tenant,
+		User:   user,
+		Client: domain.RequestMetadataFromContext(ctx),
+		Time:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if assessment.Decision == domain.RiskDeny {
+		return assessment, domain.ErrLoginDenied
+	}
+	return assessment, nil
+}
@@ -0,0 +1,189 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"midaslabs/microservices/auth/internal/domain"
+	"testing"
+	"time"
+)
+
+// scoreRule raises a signal with a fixed score, or none for zero.
+type scoreRule int
+
+func (r scoreRule) Evaluate(ctx context.Context, attempt *domain.LoginAttempt) (*domain.RiskSignal, error) {
+	if r == 0 {
+		return nil, nil
+	}
+	return &domain.RiskSignal{Name: "fixed", Score: int(r)}, nil
+}
+
+func TestRuleRiskEngineThresholds(t *testing.T) {
+	defaults := RiskThresholds{Challenge: 50, Deny: 90}
+	tests := []struct {
+		name       string
+		thresholds RiskThresholds
+		scores     []scoreRule
+		score      int
+		decision   domain.RiskDecision
+	}{
+		{name: "no signals", thresholds: defaults, score: 0, decision: domain.RiskAllow},
+		{name: "below challenge", thresholds: defaults, scores: []scoreRule{25, 20}, score: 45, decision: domain.RiskAllow},
+		{name: "at challenge", thresholds: defaults, scores: []scoreRule{25, 25}, score: 50, decision: domain.RiskChallenge},
+		{name: "between thresholds", thresholds: defaults, scores: []scoreRule{40, 30}, score: 70, decision: domain.RiskChallenge},
+		{name: "at deny", thresholds: defaults, scores: []scoreRule{40, 50}, score: 90, decision: domain.RiskDeny},
+		{name: "capped at 100", thresholds: defaults, scores: []scoreRule{80, 80}, score: 100, decision: domain.RiskDeny},
+		{name: "new device, network and inactivity", thresholds: defaults, scores: []scoreRule{25, 15, 20}, score: 60, decision: domain.RiskChallenge},
+		{name: "challenge disabled", thresholds: RiskThresholds{Deny: 90}, scores: []scoreRule{70}, score: 70, decision: domain.RiskAllow},
+		{name: "deny disabled", thresholds: RiskThresholds{Challenge: 50}, scores: []scoreRule{100}, score: 100, decision: domain.RiskChallenge},
+		{name: "both disabled", scores: []scoreRule{100}, score: 100, decision: domain.RiskAllow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := make([]domain.RiskRule, len(tt.scores))
+			for i, score := range tt.scores {
+				rules[i] = score
+			}
+			engine := NewRuleRiskEngine(tt.thresholds, rules...)
+
+			assessment, err := engine.Assess(context.Background(), &domain.LoginAttempt{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if assessment.Score != tt.score || assessment.Decision != tt.decision {
+				t.Errorf("got score %d and %s, want %d and %s", assessment.Score, assessment.Decision, tt.score, tt.decision)
+			}
+		})
+	}
+}
+
+// knownClients is a KnownClientRepository holding one user's devices and
+// networks.
+type knownClients struct {
+	domain.KnownClientRepository
+	devices  map[string]bool
+	networks map[string]bool
+}
+
+func (c *knownClients) HasKnownClients(ctx context.Context, tenantID, phoneNumber string) (bool, error) {
+	return len(c.devices) > 0 || len(c.networks) > 0, nil
+}
+
+func (c *knownClients) IsKnownDevice(ctx context.Context, tenantID, phoneNumber, deviceID string) (bool, error) {
+	return c.devices[deviceID], nil
+}
+
+func (c *knownClients) IsKnownNetwork(ctx context.Context, tenantID, phoneNumber, network string) (bool, error) {
+	return c.networks[network], nil
+}
+
+func TestNewClientRules(t *testing.T) {
+	lastLogin := time.Now().Add(-time.Hour)
+	returning := &domain.User{PhoneNumber: "+15550100", LastLoginAt: &lastLogin}
+	client := domain.RequestMetadata{DeviceID: "laptop", IP: "203.0.113.7"}
+	tracked := &knownClients{devices: map[string]bool{"phone": true}, networks: map[string]bool{"198.51.100.0/24": true}}
+
+	tests := []struct {
+		name    string
+		clients *knownClients
+		user    *domain.User
+		flagged bool
+	}{
+		{name: "new client", clients: tracked, user: returning, flagged: true},
+		{name: "known client", clients: &knownClients{devices: map[string]bool{"laptop": true}, networks: map[string]bool{"203.0.113.0/24": true}}, user: returning},
+		{name: "no known clients yet", clients: &knownClients{}, user: returning},
+		{name: "never logged in", clients: tracked, user: &domain.User{PhoneNumber: "+15550100"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempt := &domain.LoginAttempt{Tenant: &domain.Tenant{ID: "acme"}, User: tt.user, Client: client, Time: time.Now()}
+			for _, rule := range []domain.RiskRule{
+				&NewDeviceRule{ClientRepo: tt.clients, Score: 25},
+				&NewNetworkRule{ClientRepo: tt.clients, Score: 15},
+			} {
+				signal, err := rule.Evaluate(context.Background(), attempt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if flagged := signal != nil; flagged != tt.flagged {
+					t.Errorf("%T flagged = %v, want %v", rule, flagged, tt.flagged)
+				}
+			}
+		})
+	}
+}
+
+// fakeOTPRepo holds one OTP per phone number.
+type fakeOTPRepo struct {
+	domain.OTPRepository
+	otps map[string]*domain.OTP
+}
+
+func (r *fakeOTPRepo) GetOTP(ctx context.Context, tenantID, phoneNumber string) (*domain.OTP, error) {
+	otp, ok := r.otps[phoneNumber]
+	if !ok {
+		return nil, domain.ErrOTPNotFound
+	}
+	return otp, nil
+}
+
+func (r *fakeOTPRepo) DeleteOTP(ctx context.Context, tenantID, phoneNumber string) error {
+	delete(r.otps, phoneNumber)
+	return nil
+}
+
+// recoveryCodes reports whether the user has recovery codes left.
+type recoveryCodes struct {
+	domain.RecoveryCodeRepository
+	has bool
+}
+
+func (r recoveryCodes) HasRecoveryCodes(ctx context.Context, tenantID, phoneNumber string) (bool, error) {
+	return r.has, nil
+}
+
+func TestChallengedLoginWithoutSecondFactor(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback StepUpFallback
+		hasCodes bool
+		err      error
+	}{
+		{name: "recovery codes", fallback: StepUpFallbackAllow, hasCodes: true, err: domain.ErrStepUpRequired},
+		{name: "default fallback", err: domain.ErrLoginDenied},
+		{name: "deny fallback", fallback: StepUpFallbackDeny, err: domain.ErrLoginDenied},
+		{name: "allow fallback", fallback: StepUpFallbackAllow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := domain.NewUser("acme", "+15550100")
+			user.Verify()
+			activities := &fakeActivityRepo{}
+			s := &AuthService{
+				userRepo:       &fakeUserRepo{users: map[string]*domain.User{user.PhoneNumber: user}},
+				activityRepo:   activities,
+				otpRepo:        &fakeOTPRepo{otps: map[string]*domain.OTP{user.PhoneNumber: {TenantID: "acme", PhoneNumber: user.PhoneNumber, Code: "123456", Expiration: time.Now().Add(time.Minute)}}},
+				recoveryRepo:   recoveryCodes{has: tt.hasCodes},
+				clientRepo:     forgetfulClients{},
+				riskEngine:     NewRuleRiskEngine(RiskThresholds{Challenge: 50, Deny: 90}, scoreRule(60)),
+				stepUpFallback: tt.fallback,
+				transactor:     fakeTransactor{},
+				userEvents:     NewUserEvents(&fakeOutbox{}, false),
+			}
+
+			err := s.ValidatePhoneNumberLogin(tenantContext(), user.PhoneNumber, "123456", "")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if len(activities.activities) != 1 {
+				t.Fatalf("recorded %d activities, want 1", len(activities.activities))
+			}
+			if tt.hasCodes {
+				return
+			}
+			if got := activities.activities[0].Metadata["step_up"]; got != "no_second_factor" {
+				t.Errorf("step_up = %q, want no_second_factor", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	eventsv1 "midaslabs/gen/events/v1"
+	"midaslabs/microservices/auth/internal/domain"
+	"midaslabs/sdk/contracts"
+	"time"
+)
+
+// noticeNewClient remembers the device and network of a successful login.
+// When either is new to the user, it raises a login alert and notifies the
+// user. A user's first login only teaches the service their client, as does
+// the first login of a user with no remembered clients, such as accounts that
+// predate client tracking.
+func (s *AuthService) noticeNewClient(ctx context.Context, tenant *domain.Tenant, user *domain.User, firstLogin bool) (*domain.LoginAlert, error) {
+	client := domain.RequestMetadataFromContext(ctx)
+	network := domain.NetworkOf(client.IP)
+
+	if !firstLogin {
+		known, err := s.clientRepo.HasKnownClients(ctx, tenant.ID, user.PhoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		firstLogin = !known
+	}
+
+	var newDevice, newNetwork bool
+	var err error
+	if client.DeviceID != "" {
+		if newDevice, err = s.clientRepo.RememberDevice(ctx, tenant.ID, user.PhoneNumber, client.DeviceID, client.UserAgent); err != nil {
+			return nil, err
+		}
+	}
+	if network != "" {
+		if newNetwork, err = s.clientRepo.RememberNetwork(ctx, tenant.ID, user.PhoneNumber, network); err != nil {
+			return nil, err
+		}
+	}
+	if firstLogin || (!newDevice && !newNetwork) {
+		return nil, nil
+	}
+
+	id, err := generateAlertID()
+	if err != nil {
+		return nil, err
+	}
+	alert := &domain.LoginAlert{
+		ID:          id,
+		TenantID:    tenant.ID,
+		PhoneNumber: user.PhoneNumber,
+		DeviceID:    client.DeviceID,
+		UserAgent:   client.UserAgent,
+		IP:          client.IP,
+		Network:     network,
+		NewDevice:   newDevice,
+		NewNetwork:  newNetwork,
+		Status:      domain.LoginAlertPending,
+		CreatedAt:   time.Now(),
+	}
+	err = s.transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.alertRepo.CreateLoginAlert(ctx, alert); err != nil {
+			return err
+		}
+
+		// Notify the user through the OTP service
+		message, err := contracts.Marshal(&eventsv1.SecurityNotificationEvent{
+			TenantId:    tenant.ID,
+			PhoneNumber: user.PhoneNumber,
+			Sender:      tenant.SMSSender,
+			Message:     fmt.Sprintf("%s: %s. Not you? Reject it with code %s.", tenant.Name, alert.Describe(), alert.ID),
+			AlertId:     alert.ID,
+		})
+		if err != nil {
+			return err
+		}
+		return s.outbox.EnqueueMessage(ctx, contracts.TopicNotification, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return alert, nil
+}
+
+// ConfirmLogin acknowledges a login alert of the caller as their own login.
+func (s *AuthService) ConfirmLogin(ctx context.Context, phoneNumber, alertID string) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	alert, err := s.resolveLoginAlert(ctx, tenant, phoneNumber, alertID, domain.LoginAlertConfirmed)
+	if err != nil {
+		return err
+	}
+
+	// Log the confirmation activity
+	activity := &domain.Activity{
+		TenantID:    tenant.ID,
+		PhoneNumber: phoneNumber,
+		Type:        domain.ActivityLoginConfirm,
+		Metadata:    map[string]string{"alert_id": alert.ID},
+		Timestamp:   time.Now(),
+	}
+	if err := s.recordActivity(ctx, activity); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RejectLogin marks a login alert of the caller as not their own. The device
+// and network of that login are forgotten, the user's access tokens are
+// revoked, including the one issued to that login, and the account must
+// reverify before anyone can log in again: with a recovery code, or for users
+// without codes, with the SMS OTP once the tenant's cooling-off period has
+// passed.
+func (s *AuthService) RejectLogin(ctx context.Context, phoneNumber, alertID string) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	alert, err := s.resolveLoginAlert(ctx, tenant, phoneNumber, alertID, domain.LoginAlertRejected)
+	if err != nil {
+		return err
+	}
+
+	if alert.NewDevice {
+		if err := s.clientRepo.ForgetDevice(ctx, tenant.ID, phoneNumber, alert.DeviceID); err != nil {
+			return err
+		}
+	}
+	if alert.NewNetwork {
+		if err := s.clientRepo.ForgetNetwork(ctx, tenant.ID, phoneNumber, alert.Network); err != nil {
+			return err
+		}
+	}
+
+	// Drop any pending OTP so an in-flight login cannot be completed
+	if err := s.otpRepo.DeleteOTP(ctx, tenant.ID, phoneNumber); err != nil {
+		return err
+	}
+
+	// Log the rejection activity
+	activity := &domain.Activity{
+		TenantID:    tenant.ID,
+		PhoneNumber: phoneNumber,
+		Type:        domain.ActivityLoginReject,
+		Metadata:    map[string]string{"alert_id": alert.ID},
+		Timestamp:   time.Now(),
+	}
+	if err := s.recordActivity(ctx, activity); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUser(ctx, tenant.ID, phoneNumber)
+	if err != nil {
+		return err
+	}
+	user.RevokeTokens()
+	if user.RequiresReverification() || user.IsSuspended() {
+		return s.userRepo.UpdateUser(ctx, user)
+	}
+	return s.requireReverification(ctx, user, domain.ReverificationReasonLoginRejected)
+}
+
+func (s *AuthService) resolveLoginAlert(ctx context.Context, tenant *domain.Tenant, phoneNumber, alertID string, status domain.LoginAlertStatus) (*domain.LoginAlert, error) {
+	alert, err := s.alertRepo.GetLoginAlert(ctx, tenant.ID, alertID)
+	if err != nil {
+		return nil, err
+	}
+	// Do not reveal other users' alerts
+	if alert.PhoneNumber != phoneNumber {
+		return nil, domain.ErrLoginAlertNotFound
+	}
+
+	if err := alert.Resolve(status); err != nil {
+		return nil, err
+	}
+	if err := s.alertRepo.ResolveLoginAlert(ctx, alert); err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+func generateAlertID() (string, error) {
+	b := make([]byte, 5) // 5 bytes encode to 8 base32 characters
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(b), nil
+}
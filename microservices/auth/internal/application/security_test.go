@@ -0,0 +1,94 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"midaslabs/microservices/auth/api/authz"
+	"midaslabs/microservices/auth/internal/domain"
+	"testing"
+	"time"
+)
+
+// fakeAlertRepo holds login alerts by ID.
+type fakeAlertRepo struct {
+	domain.LoginAlertRepository
+	alerts map[string]*domain.LoginAlert
+}
+
+func (r *fakeAlertRepo) GetLoginAlert(ctx context.Context, tenantID, id string) (*domain.LoginAlert, error) {
+	alert, ok := r.alerts[id]
+	if !ok {
+		return nil, domain.ErrLoginAlertNotFound
+	}
+	copied := *alert
+	return &copied, nil
+}
+
+func (r *fakeAlertRepo) ResolveLoginAlert(ctx context.Context, alert *domain.LoginAlert) error {
+	r.alerts[alert.ID] = alert
+	return nil
+}
+
+// forgetfulClients forgets devices and networks without remembering any.
+type forgetfulClients struct {
+	domain.KnownClientRepository
+}
+
+func (forgetfulClients) ForgetDevice(ctx context.Context, tenantID, phoneNumber, deviceID string) error {
+	return nil
+}
+
+func (forgetfulClients) ForgetNetwork(ctx context.Context, tenantID, phoneNumber, network string) error {
+	return nil
+}
+
+// noOTPs holds no OTPs.
+type noOTPs struct {
+	domain.OTPRepository
+}
+
+func (noOTPs) DeleteOTP(ctx context.Context, tenantID, phoneNumber string) error {
+	return nil
+}
+
+func TestRejectLoginRevokesTokens(t *testing.T) {
+	lastLogin := time.Now().Add(-time.Minute)
+	user := domain.NewUser("acme", "+15550100")
+	user.LastLoginAt = &lastLogin
+	users := &fakeUserRepo{users: map[string]*domain.User{user.PhoneNumber: user}}
+	alerts := &fakeAlertRepo{alerts: map[string]*domain.LoginAlert{
+		"ALERT123": {ID: "ALERT123", TenantID: "acme", PhoneNumber: user.PhoneNumber, DeviceID: "laptop", NewDevice: true, Status: domain.LoginAlertPending},
+	}}
+	s := &AuthService{
+		userRepo:     users,
+		activityRepo: &fakeActivityRepo{},
+		otpRepo:      noOTPs{},
+		roleRepo:     &fakeRoleRepo{},
+		clientRepo:   forgetfulClients{},
+		alertRepo:    alerts,
+		outbox:       &fakeOutbox{},
+		transactor:   fakeTransactor{},
+		userEvents:   NewUserEvents(&fakeOutbox{}, false),
+	}
+	tokens := newTokens(t, s)
+	ctx := tenantContext()
+
+	// The token issued to the login the alert is about
+	token, _, err := tokens.Issue(ctx, user.PhoneNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Verify(ctx, token); err != nil {
+		t.Fatalf("token before rejection: %v", err)
+	}
+
+	if err := s.RejectLogin(ctx, user.PhoneNumber, "ALERT123"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tokens.Verify(ctx, token); !errors.Is(err, authz.ErrUnauthenticated) {
+		t.Errorf("token of the rejected login: err = %v, want %v", err, authz.ErrUnauthenticated)
+	}
+	if status := users.users[user.PhoneNumber].Status; status != domain.UserStatusReverificationRequired {
+		t.Errorf("status = %s, want %s", status, domain.UserStatusReverificationRequired)
+	}
+}
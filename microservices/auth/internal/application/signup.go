@@ -0,0 +1,121 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"midaslabs/microservices/auth/internal/domain"
+	"time"
+)
+
+// checkSignupPolicy decides whether the phone number may sign up with the tenant.
+// Under the invite code policy it consumes one use of the code.
+func (s *AuthService) checkSignupPolicy(ctx context.Context, tenant *domain.Tenant, phoneNumber, inviteCode string) error {
+	switch tenant.SignupPolicy {
+	case domain.SignupPolicyInviteCode:
+		if inviteCode == "" {
+			return domain.ErrInviteCodeRequired
+		}
+		return s.inviteRepo.RedeemInviteCode(ctx, tenant.ID, inviteCode)
+
+	case domain.SignupPolicyAllowlist:
+		allowed, err := s.allowlistRepo.IsAllowlisted(ctx, tenant.ID, phoneNumber)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return domain.ErrNotAllowlisted
+		}
+	}
+
+	return nil
+}
+
+// CreateInviteCode mints a code admitting maxUses signups. A zero validity
+// creates a code that never expires.
+func (s *AuthService) CreateInviteCode(ctx context.Context, maxUses int, validity time.Duration, actor string) (*domain.InviteCode, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, err
+	}
+
+	if maxUses <= 0 {
+		maxUses = 1
+	}
+	invite := &domain.InviteCode{
+		TenantID:  tenant.ID,
+		Code:      code,
+		MaxUses:   maxUses,
+		CreatedBy: actor,
+		CreatedAt: time.Now(),
+	}
+	if validity > 0 {
+		expiresAt := invite.CreatedAt.Add(validity)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := s.inviteRepo.CreateInviteCode(ctx, invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// ListInviteCodes returns the tenant's invite codes, newest first.
+func (s *AuthService) ListInviteCodes(ctx context.Context) ([]*domain.InviteCode, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.inviteRepo.ListInviteCodes(ctx, tenant.ID)
+}
+
+// AddAllowlistEntry admits a phone number, or every number with the given
+// prefix, under the allowlist signup policy.
+func (s *AuthService) AddAllowlistEntry(ctx context.Context, entry string, isPrefix bool, actor string) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.allowlistRepo.AddAllowlistEntry(ctx, &domain.AllowlistEntry{
+		TenantID:  tenant.ID,
+		Entry:     entry,
+		IsPrefix:  isPrefix,
+		CreatedBy: actor,
+		CreatedAt: time.Now(),
+	})
+}
+
+// RemoveAllowlistEntry removes a number or prefix from the allowlist.
+func (s *AuthService) RemoveAllowlistEntry(ctx context.Context, entry string) error {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.allowlistRepo.RemoveAllowlistEntry(ctx, tenant.ID, entry)
+}
+
+// ListAllowlistEntries returns the tenant's allowlist.
+func (s *AuthService) ListAllowlistEntries(ctx context.Context) ([]*domain.AllowlistEntry, error) {
+	tenant, err := domain.TenantFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.allowlistRepo.ListAllowlistEntries(ctx, tenant.ID)
+}
+
+func generateInviteCode() (string, error) {
+	b := make([]byte, 10) // 10 bytes encode to 16 base32 characters
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ActivityFilter narrows an activity listing. Zero values leave a criterion
+// unconstrained.
+type ActivityFilter struct {
+	PhoneNumber string
+	IP          string
+	Types       []ActivityType
+	Outcome     ActivityOutcome
+	Since       time.Time
+	Until       time.Time
+	// After resumes a listing past the activity it points to.
+	After *ActivityCursor
+	Limit int
+}
+
+// ActivityCursor is the keyset position of an activity in a listing ordered
+// newest first.
+type ActivityCursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// CursorOf returns the cursor positioned at the given activity.
+func CursorOf(activity *Activity) *ActivityCursor {
+	return &ActivityCursor{Timestamp: activity.Timestamp, ID: activity.ID}
+}
+
+// Encode returns the opaque form of the cursor handed to clients.
+func (c *ActivityCursor) Encode() string {
+	raw := fmt.Sprintf("%d:%d", c.Timestamp.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeActivityCursor parses a cursor produced by Encode.
+func DecodeActivityCursor(s string) (*ActivityCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var nanos, id int64
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &nanos, &id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &ActivityCursor{Timestamp: time.Unix(0, nanos), ID: id}, nil
+}
+
+// ActivityPage is one page of an activity listing. NextCursor is empty on the
+// last page.
+type ActivityPage struct {
+	Activities []*Activity
+	NextCursor string
+}
+
+// ExportFilter selects the activities of an export. Exports run in ID order,
+// so AfterID resumes an interrupted export past the last activity received.
+type ExportFilter struct {
+	Since   time.Time
+	Until   time.Time
+	AfterID int64
+}
+
+// ExportCursor returns the cursor resuming an export after the activity.
+func ExportCursor(activityID int64) string {
+	return strconv.FormatInt(activityID, 10)
+}
+
+// ParseExportCursor returns the activity ID an export cursor points to.
+func ParseExportCursor(s string) (int64, error) {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
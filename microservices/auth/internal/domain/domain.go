@@ -2,7 +2,6 @@ package domain
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"time"
 )
@@ -10,33 +9,124 @@ import (
 var (
 
 	// --- DATABASE ERRORS
-	ErrUserNotFound = errors.New("user not found")
-	ErrOTPNotFound  = errors.New("OTP not found")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrOTPNotFound     = errors.New("OTP not found")
+	ErrRoleNotFound    = errors.New("role not found")
+	ErrRoleNotAssigned = errors.New("role not assigned")
+	ErrTenantNotFound  = errors.New("tenant not found")
+
+	ErrAllowlistEntryNotFound = errors.New("allowlist entry not found")
+	ErrLoginAlertNotFound     = errors.New("login alert not found")
 	// -- APPLICATION ERRORS
-	ErrOTPExpired          = errors.New("OTP expired")
-	ErrInvalidOTP          = errors.New("invalid OTP")
-	ErrUserAlreadyExists   = errors.New("user already exists")
-	ErrUserNotVerified     = errors.New("user not verified")
-	ErrUserAlreadyVerified = errors.New("user already verified")
+	ErrOTPExpired             = errors.New("OTP expired")
+	ErrInvalidOTP             = errors.New("invalid OTP")
+	ErrUserAlreadyExists      = errors.New("user already exists")
+	ErrUserNotVerified        = errors.New("user not verified")
+	ErrUserAlreadyVerified    = errors.New("user already verified")
+	ErrUserSuspended          = errors.New("user suspended")
+	ErrUserNotSuspended       = errors.New("user not suspended")
+	ErrPermissionDenied       = errors.New("permission denied")
+	ErrCountryNotAllowed      = errors.New("country not allowed")
+	ErrInviteCodeRequired     = errors.New("invite code required")
+	ErrInvalidInviteCode      = errors.New("invalid invite code")
+	ErrNotAllowlisted         = errors.New("phone number not allowlisted")
+	ErrReverificationRequired = errors.New("reverification required")
+	ErrInvalidRecoveryCode    = errors.New("invalid recovery code")
+	ErrInvalidCursor          = errors.New("invalid cursor")
+	ErrLoginAlertResolved     = errors.New("login alert already resolved")
+	ErrUnknownExportFormat    = errors.New("unknown export format")
+	ErrLoginDenied            = errors.New("login denied")
+	ErrStepUpRequired         = errors.New("step-up verification required")
+	ErrReverificationPending  = errors.New("reverification cooling-off period has not elapsed")
+
+	ErrPseudonymKeyNotConfigured = errors.New("pseudonymisation key not configured")
 )
 
+// errorCodes maps domain errors to the codes stored as an activity's failure
+// reason and returned to clients.
+var errorCodes = map[error]string{
+	ErrUserNotFound:           "ERR_USER_NOT_FOUND",
+	ErrOTPNotFound:            "ERR_OTP_NOT_FOUND",
+	ErrTenantNotFound:         "ERR_TENANT_NOT_FOUND",
+	ErrOTPExpired:             "ERR_OTP_EXPIRED",
+	ErrInvalidOTP:             "ERR_INVALID_OTP",
+	ErrUserAlreadyExists:      "ERR_USER_EXISTS",
+	ErrUserNotVerified:        "ERR_USER_NOT_VERIFIED",
+	ErrUserAlreadyVerified:    "ERR_USER_ALREADY_VERIFIED",
+	ErrUserSuspended:          "ERR_USER_SUSPENDED",
+	ErrPermissionDenied:       "ERR_PERMISSION_DENIED",
+	ErrCountryNotAllowed:      "ERR_COUNTRY_NOT_ALLOWED",
+	ErrInviteCodeRequired:     "ERR_INVITE_CODE_REQUIRED",
+	ErrInvalidInviteCode:      "ERR_INVALID_INVITE_CODE",
+	ErrNotAllowlisted:         "ERR_NOT_ALLOWLISTED",
+	ErrReverificationRequired: "ERR_REVERIFICATION_REQUIRED",
+	ErrReverificationPending:  "ERR_REVERIFICATION_PENDING",
+	ErrInvalidRecoveryCode:    "ERR_INVALID_RECOVERY_CODE",
+	ErrLoginDenied:            "ERR_LOGIN_DENIED",
+	ErrStepUpRequired:         "ERR_STEP_UP_REQUIRED",
+}
+
+// ErrorCode returns the code of a domain error, or ERR_INTERNAL for anything
+// else so that infrastructure details never end up in stored activities.
+func ErrorCode(err error) string {
+	for target, code := range errorCodes {
+		if errors.Is(err, target) {
+			return code
+		}
+	}
+	return "ERR_INTERNAL"
+}
+
 type UserRepository interface {
-	GetUser(ctx context.Context, phoneNumber string) (*User, error)
+	GetUser(ctx context.Context, tenantID, phoneNumber string) (*User, error)
 	AddUser(ctx context.Context, user *User) error
 	UpdateUser(ctx context.Context, user *User) error
+	ListUsersByStatus(ctx context.Context, tenantID string, status UserStatus) ([]*User, error)
 }
 
+type UserStatus string
+
+const (
+	UserStatusActive    UserStatus = "active"
+	UserStatusSuspended UserStatus = "suspended"
+	// UserStatusReverificationRequired marks a dormant account whose number
+	// may have been reassigned; logging in needs a second factor on top of
+	// the SMS OTP.
+	UserStatusReverificationRequired UserStatus = "reverification_required"
+)
+
 type User struct {
+	TenantID    string
 	PhoneNumber string
 	Verified    bool
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	Status      UserStatus
+	Suspension  *Suspension
+	InviteCode  string
+	LastLoginAt *time.Time
+	// ReverificationRequiredAt is when the account was demoted to
+	// UserStatusReverificationRequired, while the demotion is pending. It is
+	// kept through a suspension so that reinstating restores the demotion.
+	ReverificationRequiredAt *time.Time
+	// TokensValidAfter revokes the access tokens issued to the user up to
+	// it, when set.
+	TokensValidAfter *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
-func NewUser(phoneNumber string) *User {
+// Suspension records why and by whom an account was suspended.
+type Suspension struct {
+	Reason      string
+	SuspendedBy string
+	SuspendedAt time.Time
+}
+
+func NewUser(tenantID, phoneNumber string) *User {
 	return &User{
+		TenantID:    tenantID,
 		PhoneNumber: phoneNumber,
 		Verified:    false,
+		Status:      UserStatusActive,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -51,23 +141,169 @@ func (u *User) IsVerified() bool {
 	return u.Verified
 }
 
+// Suspend freezes the account until it is reinstated and revokes its access
+// tokens.
+func (u *User) Suspend(reason, actor string) error {
+	if u.IsSuspended() {
+		return ErrUserSuspended
+	}
+	u.Status = UserStatusSuspended
+	u.Suspension = &Suspension{
+		Reason:      reason,
+		SuspendedBy: actor,
+		SuspendedAt: time.Now(),
+	}
+	u.RevokeTokens()
+	return nil
+}
+
+// Reinstate lifts a suspension, returning the account to a reverification
+// that was pending when it was suspended.
+func (u *User) Reinstate() error {
+	if !u.IsSuspended() {
+		return ErrUserNotSuspended
+	}
+	u.Status = UserStatusActive
+	if u.ReverificationRequiredAt != nil {
+		u.Status = UserStatusReverificationRequired
+	}
+	u.Suspension = nil
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+func (u *User) IsSuspended() bool {
+	return u.Status == UserStatusSuspended
+}
+
+// RevokeTokens revokes every access token issued to the user so far.
+func (u *User) RevokeTokens() {
+	now := time.Now()
+	u.TokensValidAfter = &now
+	u.UpdatedAt = now
+}
+
+// TokenRevoked reports whether an access token issued at issuedAt has been
+// revoked. Tokens carry their issue time in whole seconds, so one issued in
+// the same second as a revocation counts as revoked.
+func (u *User) TokenRevoked(issuedAt time.Time) bool {
+	return u.TokensValidAfter != nil && !issuedAt.After(u.TokensValidAfter.Truncate(time.Second))
+}
+
+// IsDormant reports whether the account has not logged in for longer than
+// period. Accounts that never logged in are measured from their creation.
+func (u *User) IsDormant(period time.Duration, now time.Time) bool {
+	if period <= 0 {
+		return false
+	}
+	last := u.CreatedAt
+	if u.LastLoginAt != nil {
+		last = *u.LastLoginAt
+	}
+	return now.Sub(last) > period
+}
+
+// RequireReverification demotes an active account so that its next login
+// needs a second factor.
+func (u *User) RequireReverification() {
+	now := time.Now()
+	u.Status = UserStatusReverificationRequired
+	u.ReverificationRequiredAt = &now
+	u.UpdatedAt = now
+}
+
+func (u *User) RequiresReverification() bool {
+	return u.Status == UserStatusReverificationRequired
+}
+
+// ReverificationCooledOff reports whether at least coolingOff has passed since
+// the account was demoted, after which a user without recovery codes may
+// reverify with the SMS OTP alone.
+func (u *User) ReverificationCooledOff(coolingOff time.Duration, now time.Time) bool {
+	if !u.RequiresReverification() || u.ReverificationRequiredAt == nil {
+		return false
+	}
+	return now.Sub(*u.ReverificationRequiredAt) >= coolingOff
+}
+
+// RecordLogin stamps a successful login and clears a pending reverification.
+func (u *User) RecordLogin() {
+	now := time.Now()
+	u.LastLoginAt = &now
+	if u.RequiresReverification() {
+		u.Status = UserStatusActive
+		u.ReverificationRequiredAt = nil
+	}
+	u.UpdatedAt = now
+}
+
+// Permission names a single capability that can be granted through a role.
+type Permission string
+
+const (
+	PermissionUsersRead    Permission = "users:read"
+	PermissionUsersSuspend Permission = "users:suspend"
+	PermissionRolesManage  Permission = "roles:manage"
+	PermissionSignupManage Permission = "signup:manage"
+
+	PermissionActivitiesRead   Permission = "activities:read"
+	PermissionActivitiesExport Permission = "activities:export"
+)
+
+type Role struct {
+	Name        string
+	Permissions []Permission
+}
+
+type RoleRepository interface {
+	GetRole(ctx context.Context, name string) (*Role, error)
+	AssignRole(ctx context.Context, tenantID, phoneNumber, role, grantedBy string) error
+	RevokeRole(ctx context.Context, tenantID, phoneNumber, role string) error
+	GetUserRoles(ctx context.Context, tenantID, phoneNumber string) ([]*Role, error)
+}
+
 type OTP struct {
+	// ID identifies the OTP in the OTP event ledger.
+	ID          string
+	TenantID    string
 	PhoneNumber string
 	Code        string
 	Expiration  time.Time
 }
 type OTPRepository interface {
-	StoreOTP(ctx context.Context, phoneNumber, otp string, expiration time.Time) error
-	GetOTP(ctx context.Context, phoneNumber string) (otp string, expiration time.Time, err error)
-	DeleteOTP(ctx context.Context, phoneNumber string) error
+	StoreOTP(ctx context.Context, otp *OTP) error
+	GetOTP(ctx context.Context, tenantID, phoneNumber string) (*OTP, error)
+	DeleteOTP(ctx context.Context, tenantID, phoneNumber string) error
 }
 
 type Activity struct {
-	PhoneNumber string
-	Type        ActivityType
-	Timestamp   time.Time
+	ID            int64
+	TenantID      string
+	PhoneNumber   string
+	Type          ActivityType
+	Actor         string
+	Metadata      map[string]string
+	Client        RequestMetadata
+	Outcome       ActivityOutcome
+	FailureReason string
+	// Risk is the assessment of a login attempt, if one was made.
+	Risk      *RiskAssessment
+	Timestamp time.Time
+
+	// PrevHash and Hash chain the activity to the previous one of the same
+	// user; see ComputeHash.
+	PrevHash    string
+	Hash        string
+	HashVersion int
 }
 
+type ActivityOutcome string
+
+const (
+	OutcomeSuccess ActivityOutcome = "success"
+	OutcomeFailure ActivityOutcome = "failure"
+)
+
 type ActivityType string
 
 const (
@@ -77,31 +313,61 @@ const (
 	ActivityVerify ActivityType = "verify"
 	ActivityUpdate ActivityType = "update"
 	ActivityDelete ActivityType = "delete"
-)
 
-type ActivityRepository interface {
-	RecordActivity(ctx context.Context, activity *Activity) error
-}
+	ActivitySuspend   ActivityType = "suspend"
+	ActivityReinstate ActivityType = "reinstate"
 
-type MessageBroker interface {
-	Publish(ctx context.Context, topic string, message []byte) error
-	Subscribe(ctx context.Context, topic string, handler func(ctx context.Context, message []byte)) error
-}
+	ActivityRoleAssign ActivityType = "role_assign"
+	ActivityRoleRevoke ActivityType = "role_revoke"
 
-type OTPVerificationEvent struct {
-	PhoneNumber string `json:"phoneNumber"`
-	OTPCode     string `json:"otpCode"`
-}
+	ActivityReverificationRequired ActivityType = "reverification_required"
+	ActivityRecoveryCodes          ActivityType = "recovery_codes"
 
-func (e *OTPVerificationEvent) Serialize() ([]byte, error) {
-	return json.Marshal(e)
+	ActivityLoginConfirm ActivityType = "login_confirm"
+	ActivityLoginReject  ActivityType = "login_reject"
+)
 
+// RecoveryCodeRepository stores single-use recovery codes, which serve as the
+// second factor when reverifying a dormant account. Only code digests are stored.
+type RecoveryCodeRepository interface {
+	ReplaceRecoveryCodes(ctx context.Context, tenantID, phoneNumber string, codeHashes []string) error
+	// ConsumeRecoveryCode marks an unused code as used, returning
+	// ErrInvalidRecoveryCode when there is none matching.
+	ConsumeRecoveryCode(ctx context.Context, tenantID, phoneNumber, codeHash string) error
+	// HasRecoveryCodes reports whether the user has any unused codes left.
+	HasRecoveryCodes(ctx context.Context, tenantID, phoneNumber string) (bool, error)
 }
 
-func DeserializeOTPEvent(data []byte) (*OTPVerificationEvent, error) {
-	var event OTPVerificationEvent
-	if err := json.Unmarshal(data, &event); err != nil {
-		return nil, err
-	}
-	return &event, nil
+type ActivityRepository interface {
+	RecordActivity(ctx context.Context, activity *Activity) error
+	// ListActivities returns up to filter.Limit activities matching the
+	// filter, newest first.
+	ListActivities(ctx context.Context, tenantID string, filter ActivityFilter) ([]*Activity, error)
+	// CountActivities returns how many activities match the filter, which
+	// must bound the time range.
+	CountActivities(ctx context.Context, tenantID string, filter ActivityFilter) (int, error)
+	// ExportActivities calls fn for every activity matching the filter in ID
+	// order, streaming rows rather than loading them. It stops at the first
+	// error fn returns.
+	ExportActivities(ctx context.Context, tenantID string, filter ExportFilter, fn func(*Activity) error) error
+	// WalkActivities calls fn for every activity of the tenant, or of all
+	// tenants when tenantID is empty, ordered by chain and then by ID. It
+	// stops at the first error fn returns.
+	WalkActivities(ctx context.Context, tenantID string, fn func(*Activity) error) error
+	// ChainHeads returns the last activity of every chain among activities
+	// with IDs up to upToID, ordered by tenant and phone number. Chains
+	// purged by retention are represented by their anchors.
+	ChainHeads(ctx context.Context, upToID int64) ([]ChainHead, error)
+	// ChainAnchors returns, for every chain whose oldest activities were
+	// purged by retention, the last purged activity.
+	ChainAnchors(ctx context.Context, tenantID string) ([]ChainHead, error)
+	// SettledActivityID returns the highest activity ID recorded before the
+	// given time, so that no transaction can still commit a lower ID.
+	SettledActivityID(ctx context.Context, before time.Time) (int64, error)
 }
+
+// Reasons an account is demoted to UserStatusReverificationRequired.
+const (
+	ReverificationReasonDormant       = "dormant"
+	ReverificationReasonLoginRejected = "login_rejected"
+)
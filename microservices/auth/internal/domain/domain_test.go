@@ -0,0 +1,38 @@
+package domain
+
+import "testing"
+
+func TestReinstateRestoresStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		demote bool
+		want   UserStatus
+	}{
+		{name: "active user", want: UserStatusActive},
+		{name: "user pending reverification", demote: true, want: UserStatusReverificationRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := NewUser("acme", "+15550100")
+			if tt.demote {
+				user.RequireReverification()
+			}
+
+			if err := user.Suspend("reported", "+15550199"); err != nil {
+				t.Fatal(err)
+			}
+			if !user.IsSuspended() {
+				t.Fatalf("status = %s after suspending", user.Status)
+			}
+			if err := user.Reinstate(); err != nil {
+				t.Fatal(err)
+			}
+			if user.Status != tt.want {
+				t.Errorf("status = %s after reinstating, want %s", user.Status, tt.want)
+			}
+			if tt.demote && user.ReverificationRequiredAt == nil {
+				t.Error("demotion time cleared")
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package domain
+
+// UserEventType names a user lifecycle event. It is also the topic, and so
+// the routing key, the event is published under. Every type carries an
+// events.v1.UserEvent.
+type UserEventType string
+
+const (
+	UserSignedUp UserEventType = "user.signed_up"
+	UserVerified UserEventType = "user.verified"
+	UserLoggedIn UserEventType = "user.logged_in"
+	// UserUpdated is raised when an account's status changes: suspension,
+	// reinstatement or a demotion to reverification.
+	UserUpdated UserEventType = "user.updated"
+	// UserDeleted is raised when retention purges a signup left unverified.
+	UserDeleted UserEventType = "user.deleted"
+)
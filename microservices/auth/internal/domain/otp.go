@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// OTPEventType is a step in the lifecycle of an OTP.
+type OTPEventType string
+
+const (
+	OTPEventIssued        OTPEventType = "issued"
+	OTPEventPublished     OTPEventType = "published"
+	OTPEventPublishFailed OTPEventType = "publish_failed"
+	// OTPEventDelivered and OTPEventDeliveryFailed are reported by the OTP
+	// service once the SMS provider accepted or refused the message.
+	OTPEventDelivered      OTPEventType = "delivered"
+	OTPEventDeliveryFailed OTPEventType = "delivery_failed"
+	// OTPEventAttempted records a code submitted against the OTP; Detail
+	// holds the result.
+	OTPEventAttempted OTPEventType = "attempted"
+	OTPEventConsumed  OTPEventType = "consumed"
+	OTPEventExpired   OTPEventType = "expired"
+)
+
+// Sources of OTP events.
+const (
+	OTPEventSourceAuth = "auth"
+	OTPEventSourceOTP  = "otp"
+)
+
+// OTPEvent is an entry in the append-only OTP ledger.
+type OTPEvent struct {
+	ID          int64
+	TenantID    string
+	OTPID       string
+	PhoneNumber string
+	Type        OTPEventType
+	Detail      string
+	Source      string
+	Timestamp   time.Time
+}
+
+type OTPEventRepository interface {
+	RecordOTPEvent(ctx context.Context, event *OTPEvent) error
+	// ListOTPEvents returns the events of a phone number since the given
+	// time, oldest first.
+	ListOTPEvents(ctx context.Context, tenantID, phoneNumber string, since time.Time, limit int) ([]*OTPEvent, error)
+}
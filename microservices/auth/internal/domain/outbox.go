@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrOutboxMessageExpired is reported for a message that expired before it
+// could be published. It is dropped rather than published.
+var ErrOutboxMessageExpired = errors.New("outbox message expired")
+
+// Transactor runs a unit of work in a database transaction. Repositories
+// called with the context handed to fn take part in the transaction; nested
+// calls join the outer transaction.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// OutboxMessage is an event waiting to be published to the message broker.
+type OutboxMessage struct {
+	ID        int64
+	Topic     string
+	Payload   []byte
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+	// ExpiresAt is when the event stops being worth delivering; nil if
+	// never.
+	ExpiresAt *time.Time
+}
+
+// OutboxRepository stores outgoing events alongside the domain changes that
+// raise them, so both are committed or neither is.
+type OutboxRepository interface {
+	EnqueueMessage(ctx context.Context, topic string, payload []byte) error
+	// EnqueueExpiringMessage queues a message that is dropped if not
+	// published by expiresAt, and that brokers discard after it.
+	EnqueueExpiringMessage(ctx context.Context, topic string, payload []byte, expiresAt time.Time) error
+	// ClaimMessages leases up to limit messages that are due for publishing,
+	// oldest first, by postponing them until leaseUntil. Other relays skip
+	// leased messages, and ones never marked are due again once the lease
+	// runs out.
+	ClaimMessages(ctx context.Context, limit int, leaseUntil time.Time) ([]*OutboxMessage, error)
+	MarkMessageSent(ctx context.Context, id int64) error
+	// MarkMessageFailed records a failed attempt and postpones the message
+	// until retryAt.
+	MarkMessageFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	// MarkMessageDead records a failed attempt and parks the message for
+	// good.
+	MarkMessageDead(ctx context.Context, id int64, reason string) error
+}
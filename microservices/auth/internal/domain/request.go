@@ -0,0 +1,26 @@
+package domain
+
+import "context"
+
+// RequestMetadata describes the client behind a request. Transports attach it
+// to the request context so activities can record where they came from.
+type RequestMetadata struct {
+	IP        string
+	UserAgent string
+	DeviceID  string
+	RequestID string
+}
+
+type requestMetadataKey struct{}
+
+// WithRequestMetadata returns a copy of ctx carrying the request metadata.
+func WithRequestMetadata(ctx context.Context, md RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataKey{}, md)
+}
+
+// RequestMetadataFromContext returns the request metadata, or the zero value
+// when the transport attached none.
+func RequestMetadataFromContext(ctx context.Context) RequestMetadata {
+	md, _ := ctx.Value(requestMetadataKey{}).(RequestMetadata)
+	return md
+}
@@ -0,0 +1,27 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RetentionRepository purges rows that are past their retention period.
+// Each call deletes at most limit rows and returns how many it deleted, or
+// the deleted users for DeleteUnverifiedUsers.
+type RetentionRepository interface {
+	DeleteExpiredOTPs(ctx context.Context, expiredBefore time.Time, limit int) (int64, error)
+	DeleteUnverifiedUsers(ctx context.Context, createdBefore time.Time, limit int) ([]*User, error)
+	// DeleteActivities deletes activities recorded before the given time in
+	// chain order, anchoring each affected chain to its last deleted
+	// activity so the remainder still verifies.
+	DeleteActivities(ctx context.Context, recordedBefore time.Time, limit int) (int64, error)
+	DeleteOTPEvents(ctx context.Context, recordedBefore time.Time, limit int) (int64, error)
+	DeleteSentOutboxMessages(ctx context.Context, sentBefore time.Time, limit int) (int64, error)
+}
+
+// LeaderLock elects a single instance to run a job across a deployment.
+type LeaderLock interface {
+	// TryLock acquires the named lock without waiting. When acquired, the
+	// returned function releases it.
+	TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error)
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RiskDecision is the outcome of assessing a login attempt.
+type RiskDecision string
+
+const (
+	RiskAllow RiskDecision = "allow"
+	// RiskChallenge lets the login proceed only with a second factor.
+	RiskChallenge RiskDecision = "challenge"
+	RiskDeny      RiskDecision = "deny"
+)
+
+// LoginStage names the step of the login flow being assessed.
+type LoginStage string
+
+const (
+	LoginStageInitiate LoginStage = "initiate"
+	LoginStageComplete LoginStage = "complete"
+)
+
+// LoginAttempt describes a login step for risk assessment.
+type LoginAttempt struct {
+	Stage  LoginStage
+	Tenant *Tenant
+	User   *User
+	Client RequestMetadata
+	Time   time.Time
+}
+
+// RiskSignal is one contribution to a risk score.
+type RiskSignal struct {
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// RiskAssessment is the scored outcome of assessing a login attempt. Scores
+// range from 0 to 100.
+type RiskAssessment struct {
+	Score    int          `json:"score"`
+	Decision RiskDecision `json:"decision"`
+	Signals  []RiskSignal `json:"signals,omitempty"`
+}
+
+// RiskEngine assesses login attempts.
+type RiskEngine interface {
+	Assess(ctx context.Context, attempt *LoginAttempt) (*RiskAssessment, error)
+}
+
+// RiskRule inspects one aspect of a login attempt. It returns a nil signal
+// when the attempt looks normal.
+type RiskRule interface {
+	Evaluate(ctx context.Context, attempt *LoginAttempt) (*RiskSignal, error)
+}
@@ -0,0 +1,134 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// KnownClientRepository remembers the devices and networks each user has
+// logged in from.
+type KnownClientRepository interface {
+	// RememberDevice records a login from the device and reports whether
+	// the device was new to the user.
+	RememberDevice(ctx context.Context, tenantID, phoneNumber, deviceID, userAgent string) (isNew bool, err error)
+	// RememberNetwork records a login from the network and reports whether
+	// the network was new to the user.
+	RememberNetwork(ctx context.Context, tenantID, phoneNumber, network string) (isNew bool, err error)
+	// HasKnownClients reports whether any device or network has been
+	// remembered for the user.
+	HasKnownClients(ctx context.Context, tenantID, phoneNumber string) (bool, error)
+	IsKnownDevice(ctx context.Context, tenantID, phoneNumber, deviceID string) (bool, error)
+	IsKnownNetwork(ctx context.Context, tenantID, phoneNumber, network string) (bool, error)
+	ForgetDevice(ctx context.Context, tenantID, phoneNumber, deviceID string) error
+	ForgetNetwork(ctx context.Context, tenantID, phoneNumber, network string) error
+}
+
+// NetworkOf returns the network an IP address belongs to for the purpose of
+// recognising returning users: its /24 for IPv4 and its /48 for IPv6. It
+// returns an empty string for an invalid address.
+func NetworkOf(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// DescribeUserAgent summarises a user agent as "Browser on OS" for people to
+// read, such as "Chrome on Linux".
+func DescribeUserAgent(userAgent string) string {
+	browser := "Unknown browser"
+	for _, b := range []struct{ token, name string }{
+		// Order matters: Edge and Opera also claim to be Chrome, and Chrome
+		// claims to be Safari.
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"okhttp", "Android app"},
+		{"CFNetwork", "iOS app"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	os := "unknown OS"
+	for _, o := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Darwin", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, o.token) {
+			os = o.name
+			break
+		}
+	}
+
+	return browser + " on " + os
+}
+
+type LoginAlertStatus string
+
+const (
+	LoginAlertPending   LoginAlertStatus = "pending"
+	LoginAlertConfirmed LoginAlertStatus = "confirmed"
+	LoginAlertRejected  LoginAlertStatus = "rejected"
+)
+
+// LoginAlert is raised when a user logs in from a device or network they
+// have not used before. The user confirms or rejects the login.
+type LoginAlert struct {
+	ID          string
+	TenantID    string
+	PhoneNumber string
+	DeviceID    string
+	UserAgent   string
+	IP          string
+	Network     string
+	NewDevice   bool
+	NewNetwork  bool
+	Status      LoginAlertStatus
+	CreatedAt   time.Time
+	ResolvedAt  *time.Time
+}
+
+// Describe returns a one-line summary of the login for the alert message.
+func (a *LoginAlert) Describe() string {
+	description := "new sign-in from " + DescribeUserAgent(a.UserAgent)
+	if a.IP != "" {
+		description += fmt.Sprintf(" (IP %s)", a.IP)
+	}
+	return description
+}
+
+// Resolve confirms or rejects a pending alert.
+func (a *LoginAlert) Resolve(status LoginAlertStatus) error {
+	if a.Status != LoginAlertPending {
+		return ErrLoginAlertResolved
+	}
+	now := time.Now()
+	a.Status = status
+	a.ResolvedAt = &now
+	return nil
+}
+
+type LoginAlertRepository interface {
+	CreateLoginAlert(ctx context.Context, alert *LoginAlert) error
+	GetLoginAlert(ctx context.Context, tenantID, id string) (*LoginAlert, error)
+	// ResolveLoginAlert stores the resolution of a pending alert, returning
+	// ErrLoginAlertResolved when it was resolved concurrently.
+	ResolveLoginAlert(ctx context.Context, alert *LoginAlert) error
+}
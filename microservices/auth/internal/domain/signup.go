@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// SignupPolicy controls who may register with a tenant.
+type SignupPolicy string
+
+const (
+	SignupPolicyOpen       SignupPolicy = "open"
+	SignupPolicyInviteCode SignupPolicy = "invite_code"
+	SignupPolicyAllowlist  SignupPolicy = "allowlist"
+)
+
+// InviteCode admits up to MaxUses signups until it expires.
+type InviteCode struct {
+	TenantID  string
+	Code      string
+	MaxUses   int
+	Uses      int
+	ExpiresAt *time.Time
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+type InviteCodeRepository interface {
+	CreateInviteCode(ctx context.Context, code *InviteCode) error
+	// RedeemInviteCode atomically consumes one use of a usable code.
+	RedeemInviteCode(ctx context.Context, tenantID, code string) error
+	ListInviteCodes(ctx context.Context, tenantID string) ([]*InviteCode, error)
+}
+
+// AllowlistEntry admits a single phone number or, when IsPrefix is set, every
+// number starting with Entry.
+type AllowlistEntry struct {
+	TenantID  string
+	Entry     string
+	IsPrefix  bool
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+type AllowlistRepository interface {
+	AddAllowlistEntry(ctx context.Context, entry *AllowlistEntry) error
+	RemoveAllowlistEntry(ctx context.Context, tenantID, entry string) error
+	ListAllowlistEntries(ctx context.Context, tenantID string) ([]*AllowlistEntry, error)
+	IsAllowlisted(ctx context.Context, tenantID, phoneNumber string) (bool, error)
+}
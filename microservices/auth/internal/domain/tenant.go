@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultOTPLength   = 8
+	DefaultOTPTTL      = 5 * time.Minute
+	DefaultSMSTemplate = "Your verification code is {code}"
+)
+
+// Tenant is one consumer app sharing the auth deployment. Users, OTPs and
+// activities are scoped by tenant.
+type Tenant struct {
+	ID          string
+	Name        string
+	OTPLength   int
+	OTPTTL      time.Duration
+	SMSSender   string
+	SMSTemplate string
+	// SignupPolicy decides whether signup is open or gated by invite codes
+	// or the allowlist.
+	SignupPolicy SignupPolicy
+	// DormancyPeriod is how long an account may go without logging in before
+	// it must be reverified. Zero disables the check.
+	DormancyPeriod time.Duration
+	// ReverificationCoolingOff is how long a demoted account without recovery
+	// codes waits before the SMS OTP alone reverifies it.
+	ReverificationCoolingOff time.Duration
+	// AllowedCountryCodes lists the E.164 country calling codes (without the
+	// leading +) that may sign up or log in. Empty allows every country.
+	AllowedCountryCodes []string
+}
+
+type TenantRepository interface {
+	GetTenant(ctx context.Context, id string) (*Tenant, error)
+	GetTenantByAPIKey(ctx context.Context, apiKey string) (*Tenant, error)
+	GetTenantByHost(ctx context.Context, host string) (*Tenant, error)
+}
+
+// AllowsPhoneNumber reports whether the phone number belongs to one of the
+// tenant's allowed countries.
+func (t *Tenant) AllowsPhoneNumber(phoneNumber string) bool {
+	if len(t.AllowedCountryCodes) == 0 {
+		return true
+	}
+	number := strings.TrimPrefix(phoneNumber, "+")
+	for _, code := range t.AllowedCountryCodes {
+		if strings.HasPrefix(number, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// OTPCodeLength returns the configured OTP length, falling back to the default.
+func (t *Tenant) OTPCodeLength() int {
+	if t.OTPLength <= 0 {
+		return DefaultOTPLength
+	}
+	return t.OTPLength
+}
+
+// OTPValidity returns the configured OTP lifetime, falling back to the default.
+func (t *Tenant) OTPValidity() time.Duration {
+	if t.OTPTTL <= 0 {
+		return DefaultOTPTTL
+	}
+	return t.OTPTTL
+}
+
+// RenderSMS fills the tenant's SMS template with the OTP code and lifetime.
+func (t *Tenant) RenderSMS(code string) string {
+	template := t.SMSTemplate
+	if template == "" {
+		template = DefaultSMSTemplate
+	}
+	minutes := strconv.Itoa(int(t.OTPValidity().Minutes()))
+	return strings.NewReplacer("{code}", code, "{ttl}", minutes).Replace(template)
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant resolved for the request.
+func WithTenant(ctx context.Context, tenant *Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant resolved for the request.
+func TenantFromContext(ctx context.Context) (*Tenant, error) {
+	tenant, ok := ctx.Value(tenantKey{}).(*Tenant)
+	if !ok || tenant == nil {
+		return nil, ErrTenantNotFound
+	}
+	return tenant, nil
+}
@@ -0,0 +1,91 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"midaslabs/microservices/auth/internal/domain"
+	"midaslabs/sdk/contracts"
+	"os"
+	"sync"
+)
+
+// CheckpointTopic is the broker topic audit checkpoints are published to.
+const CheckpointTopic = "audit.checkpoint"
+
+// FileCheckpointSink implements the CheckpointSink interface by appending
+// checkpoints as JSON lines to a file, which should live on storage the
+// database operators cannot rewrite.
+type FileCheckpointSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileCheckpointSink creates a new FileCheckpointSink.
+func NewFileCheckpointSink(path string) *FileCheckpointSink {
+	return &FileCheckpointSink{path: path}
+}
+
+func (s *FileCheckpointSink) PublishCheckpoint(ctx context.Context, checkpoint *domain.AuditCheckpoint) error {
+	line, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadCheckpoints returns the checkpoints written by a FileCheckpointSink,
+// oldest first.
+func ReadCheckpoints(path string) ([]*domain.AuditCheckpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var checkpoints []*domain.AuditCheckpoint
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var checkpoint domain.AuditCheckpoint
+		if err := json.Unmarshal(scanner.Bytes(), &checkpoint); err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, &checkpoint)
+	}
+	return checkpoints, scanner.Err()
+}
+
+// BrokerCheckpointSink implements the CheckpointSink interface by publishing
+// checkpoints to CheckpointTopic.
+type BrokerCheckpointSink struct {
+	broker contracts.MessageBroker
+}
+
+// NewBrokerCheckpointSink creates a new BrokerCheckpointSink.
+func NewBrokerCheckpointSink(broker contracts.MessageBroker) *BrokerCheckpointSink {
+	return &BrokerCheckpointSink{broker: broker}
+}
+
+func (s *BrokerCheckpointSink) PublishCheckpoint(ctx context.Context, checkpoint *domain.AuditCheckpoint) error {
+	message, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return s.broker.Publish(ctx, CheckpointTopic, message)
+}
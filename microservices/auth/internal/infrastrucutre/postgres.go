@@ -0,0 +1,109 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DBConfig describes how to reach the auth database.
+type DBConfig struct {
+	User         string
+	Password     string
+	Host         string
+	Name         string
+	Schema       string
+	MaxIdleConns int
+	MaxOpenConns int
+	DisableTLS   bool
+}
+
+// OpenDB opens a connection pool to the auth database.
+func OpenDB(cfg DBConfig) (*sqlx.DB, error) {
+	sslMode := "require"
+	if cfg.DisableTLS {
+		sslMode = "disable"
+	}
+
+	q := make(url.Values)
+	q.Set("sslmode", sslMode)
+	q.Set("timezone", "utc")
+	if cfg.Schema != "" {
+		q.Set("search_path", cfg.Schema)
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     cfg.Host,
+		Path:     cfg.Name,
+		RawQuery: q.Encode(),
+	}
+
+	db, err := sqlx.Open("pgx", u.String())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+
+	return db, nil
+}
+
+type txKey struct{}
+
+// PostgresTransactor implements the Transactor interface using PostgreSQL.
+type PostgresTransactor struct {
+	db *sqlx.DB
+}
+
+// NewPostgresTransactor creates a new PostgresTransactor.
+func NewPostgresTransactor(db *sqlx.DB) *PostgresTransactor {
+	return &PostgresTransactor{db: db}
+}
+
+func (t *PostgresTransactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+	return withinTx(ctx, t.db, func(tx *sqlx.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// querier is what repositories need from either a pool or a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// conn returns the transaction carried by ctx, so that repositories take part
+// in it, or the pool when there is none.
+func conn(ctx context.Context, db *sqlx.DB) querier {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// withinTx runs fn in the transaction carried by ctx, or in a transaction of
+// its own that it commits when fn succeeds.
+func withinTx(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(tx)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -2,7 +2,11 @@ package infrastructure
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"midaslabs/microservices/auth/internal/domain"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -17,8 +21,251 @@ func NewPostgresActivityRepository(db *sqlx.DB) *PostgresActivityRepository {
 	return &PostgresActivityRepository{db: db}
 }
 
+const activityColumns = `id, tenant_id, phone_number, type, COALESCE(actor, ''), metadata, COALESCE(host(ip), ''), COALESCE(user_agent, ''), COALESCE(device_id, ''), COALESCE(request_id, ''), outcome, COALESCE(failure_reason, ''), timestamp, COALESCE(prev_hash, ''), COALESCE(hash, ''), COALESCE(hash_version, 0), risk_score, COALESCE(risk_decision, ''), risk_signals`
+
+// RecordActivity appends the activity to its user's hash chain. Writers of
+// the same chain are serialised with a transaction-scoped advisory lock.
 func (r *PostgresActivityRepository) RecordActivity(ctx context.Context, activity *domain.Activity) error {
-	_, err := r.db.ExecContext(ctx, `INSERT INTO activities (phone_number, type, timestamp) VALUES ($1, $2, $3)`,
-		activity.PhoneNumber, activity.Type, activity.Timestamp)
-	return err
+	var metadata []byte
+	if len(activity.Metadata) > 0 {
+		var err error
+		if metadata, err = json.Marshal(activity.Metadata); err != nil {
+			return err
+		}
+	}
+	var riskScore sql.NullInt32
+	var riskDecision string
+	var riskSignals []byte
+	if risk := activity.Risk; risk != nil {
+		riskScore = sql.NullInt32{Int32: int32(risk.Score), Valid: true}
+		riskDecision = string(risk.Decision)
+		if len(risk.Signals) > 0 {
+			var err error
+			if riskSignals, err = json.Marshal(risk.Signals); err != nil {
+				return err
+			}
+		}
+	}
+
+	return withinTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2))`, activity.TenantID, activity.PhoneNumber); err != nil {
+			return err
+		}
+
+		// A chain purged entirely by retention continues from its anchor
+		var prevHash sql.NullString
+		err := tx.QueryRowContext(ctx, `SELECT COALESCE(
+				(SELECT COALESCE(hash, '') FROM activities WHERE tenant_id = $1 AND phone_number = $2 ORDER BY id DESC LIMIT 1),
+				(SELECT hash FROM activity_chain_anchors WHERE tenant_id = $1 AND phone_number = $2))`,
+			activity.TenantID, activity.PhoneNumber).Scan(&prevHash)
+		if err != nil {
+			return err
+		}
+
+		// Hash exactly what the database will keep
+		activity.Timestamp = activity.Timestamp.UTC().Truncate(time.Microsecond)
+		activity.PrevHash = prevHash.String
+		activity.HashVersion = domain.ActivityHashVersion
+		activity.Hash = activity.ComputeHash(activity.PrevHash)
+
+		client := activity.Client
+		return tx.QueryRowContext(ctx, `INSERT INTO activities (tenant_id, phone_number, type, actor, metadata, ip, user_agent, device_id, request_id, outcome, failure_reason, timestamp, prev_hash, hash, hash_version, risk_score, risk_decision, risk_signals)
+			VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, '')::inet, NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), $10, NULLIF($11, ''), $12, NULLIF($13, ''), $14, $15, $16, NULLIF($17, ''), $18)
+			RETURNING id`,
+			activity.TenantID, activity.PhoneNumber, activity.Type, activity.Actor, metadata,
+			client.IP, client.UserAgent, client.DeviceID, client.RequestID, activity.Outcome, activity.FailureReason, activity.Timestamp,
+			activity.PrevHash, activity.Hash, activity.HashVersion, riskScore, riskDecision, riskSignals).Scan(&activity.ID)
+	})
+}
+
+func (r *PostgresActivityRepository) ListActivities(ctx context.Context, tenantID string, filter domain.ActivityFilter) ([]*domain.Activity, error) {
+	where, args := activityConditions(tenantID, filter)
+	query := `SELECT ` + activityColumns + ` FROM activities WHERE ` + where +
+		fmt.Sprintf(` ORDER BY timestamp DESC, id DESC LIMIT $%d`, len(args)+1)
+	args = append(args, filter.Limit)
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activities []*domain.Activity
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+	return activities, rows.Err()
+}
+
+func (r *PostgresActivityRepository) CountActivities(ctx context.Context, tenantID string, filter domain.ActivityFilter) (int, error) {
+	where, args := activityConditions(tenantID, filter)
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COUNT(*) FROM activities WHERE `+where, args...).Scan(&count)
+	return count, err
+}
+
+// activityConditions builds the WHERE clause selecting the activities of the
+// tenant that match the filter, ignoring its limit.
+func activityConditions(tenantID string, filter domain.ActivityFilter) (string, []any) {
+	query := `tenant_id = $1`
+	args := []any{tenantID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.PhoneNumber != "" {
+		query += ` AND phone_number = ` + arg(filter.PhoneNumber)
+	}
+	if filter.IP != "" {
+		query += ` AND ip = ` + arg(filter.IP) + `::inet`
+	}
+	if len(filter.Types) > 0 {
+		types := make([]string, len(filter.Types))
+		for i, t := range filter.Types {
+			types[i] = string(t)
+		}
+		query += ` AND type = ANY(` + arg(types) + `)`
+	}
+	if filter.Outcome != "" {
+		query += ` AND outcome = ` + arg(filter.Outcome)
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND timestamp >= ` + arg(filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query += ` AND timestamp < ` + arg(filter.Until)
+	}
+	if filter.After != nil {
+		query += ` AND (timestamp, id) < (` + arg(filter.After.Timestamp) + `, ` + arg(filter.After.ID) + `)`
+	}
+	return query, args
+}
+
+func (r *PostgresActivityRepository) ExportActivities(ctx context.Context, tenantID string, filter domain.ExportFilter, fn func(*domain.Activity) error) error {
+	var since, until sql.NullTime
+	if !filter.Since.IsZero() {
+		since = sql.NullTime{Time: filter.Since, Valid: true}
+	}
+	if !filter.Until.IsZero() {
+		until = sql.NullTime{Time: filter.Until, Valid: true}
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT `+activityColumns+` FROM activities
+		WHERE ($1 = '' OR tenant_id = $1) AND id > $2
+			AND ($3::timestamptz IS NULL OR timestamp >= $3) AND ($4::timestamptz IS NULL OR timestamp < $4)
+		ORDER BY id`, tenantID, filter.AfterID, since, until)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(activity); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *PostgresActivityRepository) WalkActivities(ctx context.Context, tenantID string, fn func(*domain.Activity) error) error {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+activityColumns+` FROM activities WHERE $1 = '' OR tenant_id = $1 ORDER BY tenant_id, phone_number, id`, tenantID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(activity); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *PostgresActivityRepository) ChainHeads(ctx context.Context, upToID int64) ([]domain.ChainHead, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT DISTINCT ON (tenant_id, phone_number) tenant_id, phone_number, id, hash FROM (
+			SELECT tenant_id, phone_number, id, COALESCE(hash, '') AS hash FROM activities WHERE id <= $1
+			UNION ALL
+			SELECT tenant_id, phone_number, activity_id, hash FROM activity_chain_anchors WHERE activity_id <= $1
+		) heads
+		ORDER BY tenant_id, phone_number, id DESC`, upToID)
+	if err != nil {
+		return nil, err
+	}
+	return scanChainHeads(rows)
+}
+
+func (r *PostgresActivityRepository) ChainAnchors(ctx context.Context, tenantID string) ([]domain.ChainHead, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT tenant_id, phone_number, activity_id, hash FROM activity_chain_anchors
+		WHERE $1 = '' OR tenant_id = $1
+		ORDER BY tenant_id, phone_number`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return scanChainHeads(rows)
+}
+
+func scanChainHeads(rows *sql.Rows) ([]domain.ChainHead, error) {
+	defer rows.Close()
+
+	var heads []domain.ChainHead
+	for rows.Next() {
+		var head domain.ChainHead
+		if err := rows.Scan(&head.TenantID, &head.PhoneNumber, &head.ActivityID, &head.Hash); err != nil {
+			return nil, err
+		}
+		heads = append(heads, head)
+	}
+	return heads, rows.Err()
+}
+
+func (r *PostgresActivityRepository) SettledActivityID(ctx context.Context, before time.Time) (int64, error) {
+	var id int64
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM activities WHERE timestamp < $1`, before).Scan(&id)
+	return id, err
+}
+
+// scanActivity reads a row selected with activityColumns.
+func scanActivity(row interface{ Scan(dest ...any) error }) (*domain.Activity, error) {
+	var activity domain.Activity
+	var metadata, riskSignals []byte
+	var riskScore sql.NullInt32
+	var riskDecision string
+	if err := row.Scan(&activity.ID, &activity.TenantID, &activity.PhoneNumber, &activity.Type, &activity.Actor, &metadata,
+		&activity.Client.IP, &activity.Client.UserAgent, &activity.Client.DeviceID, &activity.Client.RequestID,
+		&activity.Outcome, &activity.FailureReason, &activity.Timestamp,
+		&activity.PrevHash, &activity.Hash, &activity.HashVersion,
+		&riskScore, &riskDecision, &riskSignals); err != nil {
+		return nil, err
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &activity.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	if riskDecision != "" {
+		activity.Risk = &domain.RiskAssessment{Score: int(riskScore.Int32), Decision: domain.RiskDecision(riskDecision)}
+		if len(riskSignals) > 0 {
+			if err := json.Unmarshal(riskSignals, &activity.Risk.Signals); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &activity, nil
 }
@@ -0,0 +1,73 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"midaslabs/microservices/auth/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresAllowlistRepository implements the AllowlistRepository interface using PostgreSQL.
+type PostgresAllowlistRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresAllowlistRepository creates a new PostgresAllowlistRepository.
+func NewPostgresAllowlistRepository(db *sqlx.DB) *PostgresAllowlistRepository {
+	return &PostgresAllowlistRepository{db: db}
+}
+
+func (r *PostgresAllowlistRepository) AddAllowlistEntry(ctx context.Context, entry *domain.AllowlistEntry) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO allowlist_entries (tenant_id, entry, is_prefix, created_by, created_at) VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+		ON CONFLICT (tenant_id, entry) DO UPDATE SET is_prefix = EXCLUDED.is_prefix`,
+		entry.TenantID, entry.Entry, entry.IsPrefix, entry.CreatedBy, entry.CreatedAt)
+	return err
+}
+
+func (r *PostgresAllowlistRepository) RemoveAllowlistEntry(ctx context.Context, tenantID, entry string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM allowlist_entries WHERE tenant_id = $1 AND entry = $2`, tenantID, entry)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrAllowlistEntryNotFound
+	}
+	return nil
+}
+
+func (r *PostgresAllowlistRepository) ListAllowlistEntries(ctx context.Context, tenantID string) ([]*domain.AllowlistEntry, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT tenant_id, entry, is_prefix, created_by, created_at FROM allowlist_entries WHERE tenant_id = $1 ORDER BY entry`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*domain.AllowlistEntry
+	for rows.Next() {
+		var entry domain.AllowlistEntry
+		var createdBy sql.NullString
+		if err := rows.Scan(&entry.TenantID, &entry.Entry, &entry.IsPrefix, &createdBy, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entry.CreatedBy = createdBy.String
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *PostgresAllowlistRepository) IsAllowlisted(ctx context.Context, tenantID, phoneNumber string) (bool, error) {
+	var allowed bool
+	row := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM allowlist_entries
+			WHERE tenant_id = $1 AND (entry = $2 OR (is_prefix AND starts_with($2, entry)))
+		)`, tenantID, phoneNumber)
+	err := row.Scan(&allowed)
+	return allowed, err
+}
@@ -0,0 +1,67 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"midaslabs/microservices/auth/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresInviteCodeRepository implements the InviteCodeRepository interface using PostgreSQL.
+type PostgresInviteCodeRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresInviteCodeRepository creates a new PostgresInviteCodeRepository.
+func NewPostgresInviteCodeRepository(db *sqlx.DB) *PostgresInviteCodeRepository {
+	return &PostgresInviteCodeRepository{db: db}
+}
+
+func (r *PostgresInviteCodeRepository) CreateInviteCode(ctx context.Context, code *domain.InviteCode) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO invite_codes (tenant_id, code, max_uses, uses, expires_at, created_by, created_at) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)`,
+		code.TenantID, code.Code, code.MaxUses, code.Uses, code.ExpiresAt, code.CreatedBy, code.CreatedAt)
+	return err
+}
+
+func (r *PostgresInviteCodeRepository) RedeemInviteCode(ctx context.Context, tenantID, code string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE invite_codes SET uses = uses + 1
+		WHERE tenant_id = $1 AND code = $2 AND uses < max_uses
+		AND (expires_at IS NULL OR expires_at > NOW())`, tenantID, code)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrInvalidInviteCode
+	}
+	return nil
+}
+
+func (r *PostgresInviteCodeRepository) ListInviteCodes(ctx context.Context, tenantID string) ([]*domain.InviteCode, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT tenant_id, code, max_uses, uses, expires_at, created_by, created_at FROM invite_codes WHERE tenant_id = $1 ORDER BY created_at DESC`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []*domain.InviteCode
+	for rows.Next() {
+		var code domain.InviteCode
+		var expiresAt sql.NullTime
+		var createdBy sql.NullString
+		if err := rows.Scan(&code.TenantID, &code.Code, &code.MaxUses, &code.Uses, &expiresAt, &createdBy, &code.CreatedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			code.ExpiresAt = &expiresAt.Time
+		}
+		code.CreatedBy = createdBy.String
+		codes = append(codes, &code)
+	}
+	return codes, rows.Err()
+}
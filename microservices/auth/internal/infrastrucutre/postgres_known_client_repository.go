@@ -0,0 +1,68 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresKnownClientRepository implements the KnownClientRepository interface using PostgreSQL.
+type PostgresKnownClientRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresKnownClientRepository creates a new PostgresKnownClientRepository.
+func NewPostgresKnownClientRepository(db *sqlx.DB) *PostgresKnownClientRepository {
+	return &PostgresKnownClientRepository{db: db}
+}
+
+// The upserts report whether they inserted the row: xmax is zero only for a
+// row version created by an insert.
+
+func (r *PostgresKnownClientRepository) RememberDevice(ctx context.Context, tenantID, phoneNumber, deviceID, userAgent string) (bool, error) {
+	var inserted bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO known_devices (tenant_id, phone_number, device_id, user_agent) VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (tenant_id, phone_number, device_id) DO UPDATE SET user_agent = EXCLUDED.user_agent, last_seen_at = NOW()
+		RETURNING xmax = 0`, tenantID, phoneNumber, deviceID, userAgent).Scan(&inserted)
+	return inserted, err
+}
+
+func (r *PostgresKnownClientRepository) RememberNetwork(ctx context.Context, tenantID, phoneNumber, network string) (bool, error) {
+	var inserted bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO known_networks (tenant_id, phone_number, network) VALUES ($1, $2, $3)
+		ON CONFLICT (tenant_id, phone_number, network) DO UPDATE SET last_seen_at = NOW()
+		RETURNING xmax = 0`, tenantID, phoneNumber, network).Scan(&inserted)
+	return inserted, err
+}
+
+func (r *PostgresKnownClientRepository) HasKnownClients(ctx context.Context, tenantID, phoneNumber string) (bool, error) {
+	var known bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM known_devices WHERE tenant_id = $1 AND phone_number = $2)
+			OR EXISTS (SELECT 1 FROM known_networks WHERE tenant_id = $1 AND phone_number = $2)`, tenantID, phoneNumber).Scan(&known)
+	return known, err
+}
+
+func (r *PostgresKnownClientRepository) IsKnownDevice(ctx context.Context, tenantID, phoneNumber, deviceID string) (bool, error) {
+	var known bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM known_devices WHERE tenant_id = $1 AND phone_number = $2 AND device_id = $3)`, tenantID, phoneNumber, deviceID).Scan(&known)
+	return known, err
+}
+
+func (r *PostgresKnownClientRepository) IsKnownNetwork(ctx context.Context, tenantID, phoneNumber, network string) (bool, error) {
+	var known bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM known_networks WHERE tenant_id = $1 AND phone_number = $2 AND network = $3)`, tenantID, phoneNumber, network).Scan(&known)
+	return known, err
+}
+
+func (r *PostgresKnownClientRepository) ForgetDevice(ctx context.Context, tenantID, phoneNumber, deviceID string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM known_devices WHERE tenant_id = $1 AND phone_number = $2 AND device_id = $3`, tenantID, phoneNumber, deviceID)
+	return err
+}
+
+func (r *PostgresKnownClientRepository) ForgetNetwork(ctx context.Context, tenantID, phoneNumber, network string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM known_networks WHERE tenant_id = $1 AND phone_number = $2 AND network = $3`, tenantID, phoneNumber, network)
+	return err
+}
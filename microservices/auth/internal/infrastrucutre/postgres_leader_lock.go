@@ -0,0 +1,47 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresLeaderLock implements the LeaderLock interface with session-level
+// advisory locks. Each held lock pins one connection of the pool, and the
+// lock is released by the server should the instance die.
+type PostgresLeaderLock struct {
+	db *sqlx.DB
+}
+
+// NewPostgresLeaderLock creates a new PostgresLeaderLock.
+func NewPostgresLeaderLock(db *sqlx.DB) *PostgresLeaderLock {
+	return &PostgresLeaderLock{db: db}
+}
+
+func (l *PostgresLeaderLock) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := l.db.Connx(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtext($1))`, name).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// Use a fresh context so a cancelled job still releases its lock
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, name); err != nil {
+			// Discard the connection rather than pool it with the lock held
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,64 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"midaslabs/microservices/auth/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresLoginAlertRepository implements the LoginAlertRepository interface using PostgreSQL.
+type PostgresLoginAlertRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresLoginAlertRepository creates a new PostgresLoginAlertRepository.
+func NewPostgresLoginAlertRepository(db *sqlx.DB) *PostgresLoginAlertRepository {
+	return &PostgresLoginAlertRepository{db: db}
+}
+
+func (r *PostgresLoginAlertRepository) CreateLoginAlert(ctx context.Context, alert *domain.LoginAlert) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `
+		INSERT INTO login_alerts (id, tenant_id, phone_number, device_id, user_agent, ip, network, new_device, new_network, status, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, '')::inet, NULLIF($7, '')::cidr, $8, $9, $10, $11)`,
+		alert.ID, alert.TenantID, alert.PhoneNumber, alert.DeviceID, alert.UserAgent, alert.IP, alert.Network,
+		alert.NewDevice, alert.NewNetwork, alert.Status, alert.CreatedAt)
+	return err
+}
+
+func (r *PostgresLoginAlertRepository) GetLoginAlert(ctx context.Context, tenantID, id string) (*domain.LoginAlert, error) {
+	var alert domain.LoginAlert
+	var resolvedAt sql.NullTime
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		SELECT id, tenant_id, phone_number, COALESCE(device_id, ''), COALESCE(user_agent, ''), COALESCE(host(ip), ''), COALESCE(network::text, ''), new_device, new_network, status, created_at, resolved_at
+		FROM login_alerts WHERE tenant_id = $1 AND id = $2`, tenantID, id).Scan(
+		&alert.ID, &alert.TenantID, &alert.PhoneNumber, &alert.DeviceID, &alert.UserAgent, &alert.IP, &alert.Network,
+		&alert.NewDevice, &alert.NewNetwork, &alert.Status, &alert.CreatedAt, &resolvedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrLoginAlertNotFound
+		}
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		alert.ResolvedAt = &resolvedAt.Time
+	}
+	return &alert, nil
+}
+
+func (r *PostgresLoginAlertRepository) ResolveLoginAlert(ctx context.Context, alert *domain.LoginAlert) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE login_alerts SET status = $1, resolved_at = $2 WHERE tenant_id = $3 AND id = $4 AND status = 'pending'`,
+		alert.Status, alert.ResolvedAt, alert.TenantID, alert.ID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrLoginAlertResolved
+	}
+	return nil
+}
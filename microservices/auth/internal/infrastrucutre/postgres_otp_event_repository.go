@@ -0,0 +1,51 @@
+package infrastructure
+
+import (
+	"context"
+	"midaslabs/microservices/auth/internal/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresOTPEventRepository implements the OTPEventRepository interface using PostgreSQL.
+type PostgresOTPEventRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresOTPEventRepository creates a new PostgresOTPEventRepository.
+func NewPostgresOTPEventRepository(db *sqlx.DB) *PostgresOTPEventRepository {
+	return &PostgresOTPEventRepository{db: db}
+}
+
+func (r *PostgresOTPEventRepository) RecordOTPEvent(ctx context.Context, event *domain.OTPEvent) error {
+	return conn(ctx, r.db).QueryRowContext(ctx, `
+		INSERT INTO otp_events (tenant_id, otp_id, phone_number, type, detail, source, timestamp)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+		RETURNING id`,
+		event.TenantID, event.OTPID, event.PhoneNumber, event.Type, event.Detail, event.Source, event.Timestamp).Scan(&event.ID)
+}
+
+func (r *PostgresOTPEventRepository) ListOTPEvents(ctx context.Context, tenantID, phoneNumber string, since time.Time, limit int) ([]*domain.OTPEvent, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT id, tenant_id, otp_id, phone_number, type, COALESCE(detail, ''), source, timestamp FROM (
+			SELECT * FROM otp_events
+			WHERE tenant_id = $1 AND phone_number = $2 AND timestamp >= $3
+			ORDER BY timestamp DESC, id DESC LIMIT $4
+		) latest
+		ORDER BY timestamp, id`, tenantID, phoneNumber, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*domain.OTPEvent
+	for rows.Next() {
+		var event domain.OTPEvent
+		if err := rows.Scan(&event.ID, &event.TenantID, &event.OTPID, &event.PhoneNumber, &event.Type, &event.Detail, &event.Source, &event.Timestamp); err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
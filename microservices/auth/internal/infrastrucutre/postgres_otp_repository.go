@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"midaslabs/microservices/auth/internal/domain"
-	"time"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -19,22 +18,26 @@ func NewPostgresOTPRepository(db *sqlx.DB) *PostgresOTPRepository {
 	return &PostgresOTPRepository{db: db}
 }
 
-func (r *PostgresOTPRepository) StoreOTP(ctx context.Context, phoneNumber, otp string, expiration time.Time) error {
-	_, err := r.db.ExecContext(ctx, `INSERT INTO otps (phone_number, code, expiration) VALUES ($1, $2, $3)`,
-		phoneNumber, otp, expiration)
+func (r *PostgresOTPRepository) StoreOTP(ctx context.Context, otp *domain.OTP) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO otps (id, tenant_id, phone_number, code, expiration) VALUES (NULLIF($1, ''), $2, $3, $4, $5)`,
+		otp.ID, otp.TenantID, otp.PhoneNumber, otp.Code, otp.Expiration)
 	return err
 }
 
-func (r *PostgresOTPRepository) GetOTP(ctx context.Context, phoneNumber string) (otp string, expiration time.Time, err error) {
-	row := r.db.QueryRowContext(ctx, `SELECT code, expiration FROM otps WHERE phone_number = $1`, phoneNumber)
-	err = row.Scan(&otp, &expiration)
+func (r *PostgresOTPRepository) GetOTP(ctx context.Context, tenantID, phoneNumber string) (*domain.OTP, error) {
+	otp := domain.OTP{TenantID: tenantID, PhoneNumber: phoneNumber}
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT COALESCE(id, ''), code, expiration FROM otps WHERE tenant_id = $1 AND phone_number = $2`, tenantID, phoneNumber)
+	err := row.Scan(&otp.ID, &otp.Code, &otp.Expiration)
 	if err == sql.ErrNoRows {
-		return "", time.Time{}, domain.ErrOTPNotFound
+		return nil, domain.ErrOTPNotFound
 	}
-	return otp, expiration, err
+	if err != nil {
+		return nil, err
+	}
+	return &otp, nil
 }
 
-func (r *PostgresOTPRepository) DeleteOTP(ctx context.Context, phoneNumber string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM otps WHERE phone_number = $1`, phoneNumber)
+func (r *PostgresOTPRepository) DeleteOTP(ctx context.Context, tenantID, phoneNumber string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM otps WHERE tenant_id = $1 AND phone_number = $2`, tenantID, phoneNumber)
 	return err
 }
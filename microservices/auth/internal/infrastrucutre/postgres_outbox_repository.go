@@ -0,0 +1,86 @@
+package infrastructure
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"midaslabs/microservices/auth/internal/domain"
+	"slices"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresOutboxRepository implements the OutboxRepository interface using PostgreSQL.
+type PostgresOutboxRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresOutboxRepository creates a new PostgresOutboxRepository.
+func NewPostgresOutboxRepository(db *sqlx.DB) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{db: db}
+}
+
+func (r *PostgresOutboxRepository) EnqueueMessage(ctx context.Context, topic string, payload []byte) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO outbox (topic, payload) VALUES ($1, $2)`, topic, payload)
+	return err
+}
+
+func (r *PostgresOutboxRepository) EnqueueExpiringMessage(ctx context.Context, topic string, payload []byte, expiresAt time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO outbox (topic, payload, expires_at) VALUES ($1, $2, $3)`, topic, payload, expiresAt)
+	return err
+}
+
+// ClaimMessages takes its row locks only for the statement, so no lock is
+// held while the claimed messages are published.
+func (r *PostgresOutboxRepository) ClaimMessages(ctx context.Context, limit int, leaseUntil time.Time) ([]*domain.OutboxMessage, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		WITH due AS (
+			SELECT id FROM outbox
+			WHERE sent_at IS NULL AND dead_at IS NULL AND next_attempt_at <= NOW()
+			ORDER BY id LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE outbox SET next_attempt_at = $2
+		FROM due WHERE outbox.id = due.id
+		RETURNING outbox.id, topic, payload, attempts, COALESCE(last_error, ''), created_at, expires_at`, limit, leaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*domain.OutboxMessage
+	for rows.Next() {
+		var message domain.OutboxMessage
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&message.ID, &message.Topic, &message.Payload, &message.Attempts, &message.LastError, &message.CreatedAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			message.ExpiresAt = &expiresAt.Time
+		}
+		messages = append(messages, &message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not keep the order of the subquery
+	slices.SortFunc(messages, func(a, b *domain.OutboxMessage) int { return cmp.Compare(a.ID, b.ID) })
+	return messages, nil
+}
+
+func (r *PostgresOutboxRepository) MarkMessageSent(ctx context.Context, id int64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE outbox SET sent_at = NOW(), attempts = attempts + 1 WHERE id = $1`, id)
+	return err
+}
+
+func (r *PostgresOutboxRepository) MarkMessageFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1`, id, reason, retryAt)
+	return err
+}
+
+func (r *PostgresOutboxRepository) MarkMessageDead(ctx context.Context, id int64, reason string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE outbox SET dead_at = NOW(), attempts = attempts + 1, last_error = $2 WHERE id = $1`, id, reason)
+	return err
+}
@@ -0,0 +1,59 @@
+package infrastructure
+
+import (
+	"context"
+	"midaslabs/microservices/auth/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresRecoveryCodeRepository implements the RecoveryCodeRepository interface using PostgreSQL.
+type PostgresRecoveryCodeRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresRecoveryCodeRepository creates a new PostgresRecoveryCodeRepository.
+func NewPostgresRecoveryCodeRepository(db *sqlx.DB) *PostgresRecoveryCodeRepository {
+	return &PostgresRecoveryCodeRepository{db: db}
+}
+
+func (r *PostgresRecoveryCodeRepository) ReplaceRecoveryCodes(ctx context.Context, tenantID, phoneNumber string, codeHashes []string) error {
+	return withinTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE tenant_id = $1 AND phone_number = $2`, tenantID, phoneNumber); err != nil {
+			return err
+		}
+		for _, hash := range codeHashes {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (tenant_id, phone_number, code_hash) VALUES ($1, $2, $3)`, tenantID, phoneNumber, hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *PostgresRecoveryCodeRepository) ConsumeRecoveryCode(ctx context.Context, tenantID, phoneNumber, codeHash string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE id = (
+			SELECT id FROM recovery_codes
+			WHERE tenant_id = $1 AND phone_number = $2 AND code_hash = $3 AND used_at IS NULL
+			LIMIT 1
+		) AND used_at IS NULL`, tenantID, phoneNumber, codeHash)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrInvalidRecoveryCode
+	}
+	return nil
+}
+
+func (r *PostgresRecoveryCodeRepository) HasRecoveryCodes(ctx context.Context, tenantID, phoneNumber string) (bool, error) {
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM recovery_codes WHERE tenant_id = $1 AND phone_number = $2 AND used_at IS NULL)`, tenantID, phoneNumber).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,104 @@
+package infrastructure
+
+import (
+	"context"
+	"midaslabs/microservices/auth/internal/domain"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresRetentionRepository implements the RetentionRepository interface using PostgreSQL.
+type PostgresRetentionRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresRetentionRepository creates a new PostgresRetentionRepository.
+func NewPostgresRetentionRepository(db *sqlx.DB) *PostgresRetentionRepository {
+	return &PostgresRetentionRepository{db: db}
+}
+
+// DeleteExpiredOTPs records each purged OTP as expired in the OTP ledger.
+func (r *PostgresRetentionRepository) DeleteExpiredOTPs(ctx context.Context, expiredBefore time.Time, limit int) (int64, error) {
+	var n int64
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		WITH deleted AS (
+			DELETE FROM otps WHERE (tenant_id, phone_number) IN (
+				SELECT tenant_id, phone_number FROM otps WHERE expiration < $1 LIMIT $2
+			)
+			RETURNING tenant_id, phone_number, id
+		), logged AS (
+			INSERT INTO otp_events (tenant_id, otp_id, phone_number, type, detail, source)
+			SELECT tenant_id, id, phone_number, 'expired', 'purged unused', 'auth' FROM deleted WHERE id IS NOT NULL
+		)
+		SELECT COUNT(*) FROM deleted`, expiredBefore, limit).Scan(&n)
+	return n, err
+}
+
+func (r *PostgresRetentionRepository) DeleteUnverifiedUsers(ctx context.Context, createdBefore time.Time, limit int) ([]*domain.User, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		DELETE FROM users WHERE (tenant_id, phone_number) IN (
+			SELECT tenant_id, phone_number FROM users WHERE NOT verified AND created_at < $1 LIMIT $2
+		)
+		RETURNING `+userColumns, createdBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// DeleteActivities deletes by ID rather than by timestamp, so every chain
+// loses a prefix and never a row from its middle.
+func (r *PostgresRetentionRepository) DeleteActivities(ctx context.Context, recordedBefore time.Time, limit int) (int64, error) {
+	var n int64
+	err := conn(ctx, r.db).QueryRowContext(ctx, `
+		WITH doomed AS (
+			SELECT id FROM activities
+			WHERE id <= (SELECT COALESCE(MAX(id), 0) FROM activities WHERE timestamp < $1)
+			ORDER BY id LIMIT $2
+		), deleted AS (
+			DELETE FROM activities a USING doomed WHERE a.id = doomed.id
+			RETURNING a.tenant_id, a.phone_number, a.id, COALESCE(a.hash, '') AS hash
+		), anchored AS (
+			INSERT INTO activity_chain_anchors (tenant_id, phone_number, activity_id, hash)
+			SELECT DISTINCT ON (tenant_id, phone_number) tenant_id, phone_number, id, hash
+			FROM deleted ORDER BY tenant_id, phone_number, id DESC
+			ON CONFLICT (tenant_id, phone_number) DO UPDATE
+			SET activity_id = EXCLUDED.activity_id, hash = EXCLUDED.hash
+			WHERE activity_chain_anchors.activity_id < EXCLUDED.activity_id
+		)
+		SELECT COUNT(*) FROM deleted`, recordedBefore, limit).Scan(&n)
+	return n, err
+}
+
+func (r *PostgresRetentionRepository) DeleteOTPEvents(ctx context.Context, recordedBefore time.Time, limit int) (int64, error) {
+	return r.exec(ctx, `
+		DELETE FROM otp_events WHERE id IN (
+			SELECT id FROM otp_events WHERE timestamp < $1 LIMIT $2
+		)`, recordedBefore, limit)
+}
+
+func (r *PostgresRetentionRepository) DeleteSentOutboxMessages(ctx context.Context, sentBefore time.Time, limit int) (int64, error) {
+	return r.exec(ctx, `
+		DELETE FROM outbox WHERE id IN (
+			SELECT id FROM outbox WHERE sent_at < $1 LIMIT $2
+		)`, sentBefore, limit)
+}
+
+func (r *PostgresRetentionRepository) exec(ctx context.Context, query string, args ...any) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
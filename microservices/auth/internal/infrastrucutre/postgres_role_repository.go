@@ -0,0 +1,97 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"midaslabs/microservices/auth/internal/domain"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresRoleRepository implements the RoleRepository interface using PostgreSQL.
+type PostgresRoleRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresRoleRepository creates a new PostgresRoleRepository.
+func NewPostgresRoleRepository(db *sqlx.DB) *PostgresRoleRepository {
+	return &PostgresRoleRepository{db: db}
+}
+
+func (r *PostgresRoleRepository) GetRole(ctx context.Context, name string) (*domain.Role, error) {
+	var exists bool
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT true FROM roles WHERE name = $1`, name)
+	if err := row.Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrRoleNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT permission FROM role_permissions WHERE role = $1 ORDER BY permission`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	role := &domain.Role{Name: name}
+	for rows.Next() {
+		var permission domain.Permission
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		role.Permissions = append(role.Permissions, permission)
+	}
+	return role, rows.Err()
+}
+
+func (r *PostgresRoleRepository) AssignRole(ctx context.Context, tenantID, phoneNumber, role, grantedBy string) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO user_roles (tenant_id, phone_number, role, granted_by) VALUES ($1, $2, $3, NULLIF($4, '')) ON CONFLICT DO NOTHING`,
+		tenantID, phoneNumber, role, grantedBy)
+	return err
+}
+
+func (r *PostgresRoleRepository) RevokeRole(ctx context.Context, tenantID, phoneNumber, role string) error {
+	res, err := conn(ctx, r.db).ExecContext(ctx, `DELETE FROM user_roles WHERE tenant_id = $1 AND phone_number = $2 AND role = $3`, tenantID, phoneNumber, role)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrRoleNotAssigned
+	}
+	return nil
+}
+
+func (r *PostgresRoleRepository) GetUserRoles(ctx context.Context, tenantID, phoneNumber string) ([]*domain.Role, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `
+		SELECT ur.role, rp.permission
+		FROM user_roles ur
+		LEFT JOIN role_permissions rp ON rp.role = ur.role
+		WHERE ur.tenant_id = $1 AND ur.phone_number = $2
+		ORDER BY ur.role, rp.permission`, tenantID, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*domain.Role
+	for rows.Next() {
+		var name string
+		var permission sql.NullString
+		if err := rows.Scan(&name, &permission); err != nil {
+			return nil, err
+		}
+		if len(roles) == 0 || roles[len(roles)-1].Name != name {
+			roles = append(roles, &domain.Role{Name: name})
+		}
+		if permission.Valid {
+			role := roles[len(roles)-1]
+			role.Permissions = append(role.Permissions, domain.Permission(permission.String))
+		}
+	}
+	return roles, rows.Err()
+}
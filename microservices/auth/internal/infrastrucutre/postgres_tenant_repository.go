@@ -0,0 +1,71 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"midaslabs/microservices/auth/internal/domain"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// PostgresTenantRepository implements the TenantRepository interface using PostgreSQL.
+type PostgresTenantRepository struct {
+	db *sqlx.DB
+}
+
+// NewPostgresTenantRepository creates a new PostgresTenantRepository.
+func NewPostgresTenantRepository(db *sqlx.DB) *PostgresTenantRepository {
+	return &PostgresTenantRepository{db: db}
+}
+
+const tenantColumns = `id, name, otp_length, otp_ttl_seconds, sms_sender, sms_template, allowed_country_codes, signup_policy, dormancy_days, reverification_cooling_off_hours`
+
+func (r *PostgresTenantRepository) GetTenant(ctx context.Context, id string) (*domain.Tenant, error) {
+	return r.getTenant(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE id = $1`, id)
+}
+
+// GetTenantByAPIKey looks the tenant up by the SHA-256 digest of its API key;
+// raw keys are never stored.
+func (r *PostgresTenantRepository) GetTenantByAPIKey(ctx context.Context, apiKey string) (*domain.Tenant, error) {
+	sum := sha256.Sum256([]byte(apiKey))
+	return r.getTenant(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE api_key_hash = $1`, hex.EncodeToString(sum[:]))
+}
+
+func (r *PostgresTenantRepository) GetTenantByHost(ctx context.Context, host string) (*domain.Tenant, error) {
+	return r.getTenant(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE host = $1`, strings.ToLower(host))
+}
+
+func (r *PostgresTenantRepository) getTenant(ctx context.Context, query string, arg any) (*domain.Tenant, error) {
+	var tenant domain.Tenant
+	var ttlSeconds, dormancyDays, coolingOffHours int
+	var countryCodes string
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, arg)
+	if err := row.Scan(&tenant.ID, &tenant.Name, &tenant.OTPLength, &ttlSeconds, &tenant.SMSSender, &tenant.SMSTemplate, &countryCodes, &tenant.SignupPolicy, &dormancyDays, &coolingOffHours); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrTenantNotFound
+		}
+		return nil, err
+	}
+	tenant.OTPTTL = time.Duration(ttlSeconds) * time.Second
+	tenant.DormancyPeriod = time.Duration(dormancyDays) * 24 * time.Hour
+	tenant.ReverificationCoolingOff = time.Duration(coolingOffHours) * time.Hour
+	tenant.AllowedCountryCodes = parseCountryCodes(countryCodes)
+	return &tenant, nil
+}
+
+// parseCountryCodes splits a comma-separated list such as "+1, +44" into
+// codes without the leading +, dropping empty entries.
+func parseCountryCodes(list string) []string {
+	var codes []string
+	for _, code := range strings.Split(list, ",") {
+		code = strings.TrimPrefix(strings.TrimSpace(code), "+")
+		if code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
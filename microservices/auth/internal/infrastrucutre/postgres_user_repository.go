@@ -18,26 +18,81 @@ func NewPostgresUserRepository(db *sqlx.DB) *PostgresUserRepository {
 	return &PostgresUserRepository{db: db}
 }
 
-func (r *PostgresUserRepository) GetUser(ctx context.Context, phoneNumber string) (*domain.User, error) {
-	var user domain.User
-	row := r.db.QueryRowContext(ctx, `SELECT phone_number, verified, created_at, updated_at FROM users WHERE phone_number = $1`, phoneNumber)
-	if err := row.Scan(&user.PhoneNumber, &user.Verified, &user.CreatedAt, &user.UpdatedAt); err != nil {
+const userColumns = `tenant_id, phone_number, verified, status, suspension_reason, suspended_by, suspended_at, invite_code, last_login_at, reverification_required_at, tokens_valid_after, created_at, updated_at`
+
+func (r *PostgresUserRepository) GetUser(ctx context.Context, tenantID, phoneNumber string) (*domain.User, error) {
+	row := conn(ctx, r.db).QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE tenant_id = $1 AND phone_number = $2`, tenantID, phoneNumber)
+	user, err := scanUser(row)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, domain.ErrUserNotFound
 		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (r *PostgresUserRepository) AddUser(ctx context.Context, user *domain.User) error {
-	_, err := r.db.ExecContext(ctx, `INSERT INTO users (phone_number, verified, created_at, updated_at) VALUES ($1, $2, $3, $4)`,
-		user.PhoneNumber, user.Verified, user.CreatedAt, user.UpdatedAt)
+	_, err := conn(ctx, r.db).ExecContext(ctx, `INSERT INTO users (tenant_id, phone_number, verified, status, invite_code, created_at, updated_at) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)`,
+		user.TenantID, user.PhoneNumber, user.Verified, user.Status, user.InviteCode, user.CreatedAt, user.UpdatedAt)
 	return err
 }
 
 func (r *PostgresUserRepository) UpdateUser(ctx context.Context, user *domain.User) error {
-	_, err := r.db.ExecContext(ctx, `UPDATE users SET verified = $1, updated_at = $2 WHERE phone_number = $3`,
-		user.Verified, user.UpdatedAt, user.PhoneNumber)
+	var reason, suspendedBy sql.NullString
+	var suspendedAt sql.NullTime
+	if user.Suspension != nil {
+		reason = sql.NullString{String: user.Suspension.Reason, Valid: true}
+		suspendedBy = sql.NullString{String: user.Suspension.SuspendedBy, Valid: true}
+		suspendedAt = sql.NullTime{Time: user.Suspension.SuspendedAt, Valid: true}
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, `UPDATE users SET verified = $1, status = $2, suspension_reason = $3, suspended_by = $4, suspended_at = $5, last_login_at = $6, reverification_required_at = $7, tokens_valid_after = $8, updated_at = $9 WHERE tenant_id = $10 AND phone_number = $11`,
+		user.Verified, user.Status, reason, suspendedBy, suspendedAt, user.LastLoginAt, user.ReverificationRequiredAt, user.TokensValidAfter, user.UpdatedAt, user.TenantID, user.PhoneNumber)
 	return err
 }
+
+func (r *PostgresUserRepository) ListUsersByStatus(ctx context.Context, tenantID string, status domain.UserStatus) ([]*domain.User, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT `+userColumns+` FROM users WHERE tenant_id = $1 AND status = $2 ORDER BY updated_at DESC`, tenantID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*domain.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// scanUser reads a row selected with userColumns.
+func scanUser(row interface{ Scan(dest ...any) error }) (*domain.User, error) {
+	var user domain.User
+	var reason, suspendedBy, inviteCode sql.NullString
+	var suspendedAt, lastLoginAt, reverificationRequiredAt, tokensValidAfter sql.NullTime
+	if err := row.Scan(&user.TenantID, &user.PhoneNumber, &user.Verified, &user.Status, &reason, &suspendedBy, &suspendedAt, &inviteCode, &lastLoginAt, &reverificationRequiredAt, &tokensValidAfter, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if lastLoginAt.Valid {
+		user.LastLoginAt = &lastLoginAt.Time
+	}
+	if reverificationRequiredAt.Valid {
+		user.ReverificationRequiredAt = &reverificationRequiredAt.Time
+	}
+	if tokensValidAfter.Valid {
+		user.TokensValidAfter = &tokensValidAfter.Time
+	}
+	user.InviteCode = inviteCode.String
+	if suspendedAt.Valid {
+		user.Suspension = &domain.Suspension{
+			Reason:      reason.String,
+			SuspendedBy: suspendedBy.String,
+			SuspendedAt: suspendedAt.Time,
+		}
+	}
+	return &user, nil
+}
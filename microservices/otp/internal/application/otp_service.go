@@ -2,17 +2,35 @@ package application
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"log"
+	"time"
 
+	eventsv1 "midaslabs/gen/events/v1"
 	"midaslabs/microservices/otp/internal/domain"
+	"midaslabs/sdk/contracts"
+	"midaslabs/sdk/envelope"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// errOTPExpired is reported to the auth service for codes that expired
+// before they could be sent.
+var errOTPExpired = errors.New("OTP expired before sending")
+
+// otpStats counts the OTPs of every OTPService in the process. It is
+// published once, as expvar panics on duplicate names.
+var otpStats = expvar.NewMap("otp")
+
 type OTPService struct {
-	messageBroker domain.MessageBroker
+	messageBroker contracts.MessageBroker
 	otpClient     domain.OTPServiceClient
 }
 
-func NewOTPService(messageBroker domain.MessageBroker, otpClient domain.OTPServiceClient) *OTPService {
+// NewOTPService creates an OTPService. Counts of sent, failed and
+// dropped_expired OTPs are published through expvar under "otp".
+func NewOTPService(messageBroker contracts.MessageBroker, otpClient domain.OTPServiceClient) *OTPService {
 	return &OTPService{
 		messageBroker: messageBroker,
 		otpClient:     otpClient,
@@ -20,25 +38,93 @@ func NewOTPService(messageBroker domain.MessageBroker, otpClient domain.OTPServi
 }
 
 func (s *OTPService) Start(ctx context.Context) error {
-	return s.messageBroker.Subscribe(ctx, "verification", s.handleOTPEvent)
+	// New versions of an event are handled alongside the old ones by
+	// registering another handler
+	verification := envelope.NewRouter(nil)
+	verification.Handle(contracts.TopicVerification, "1", s.handleOTPEvent)
+	if err := s.messageBroker.Subscribe(ctx, contracts.TopicVerification, verification.Dispatch); err != nil {
+		return err
+	}
+
+	notification := envelope.NewRouter(nil)
+	notification.Handle(contracts.TopicNotification, "1", s.handleNotificationEvent)
+	return s.messageBroker.Subscribe(ctx, contracts.TopicNotification, notification.Dispatch)
 }
 
-func (s *OTPService) handleOTPEvent(ctx context.Context, message []byte) {
-	event, err := domain.DeserializeOTPEvent(message)
-	if err != nil {
+func (s *OTPService) handleOTPEvent(ctx context.Context, message []byte) error {
+	event := &eventsv1.OTPVerificationEvent{}
+	if err := contracts.Unmarshal(ctx, message, event); err != nil {
 		log.Printf("Failed to deserialize OTP event: %v", err)
-		return
+		return err
+	}
+
+	// A code texted after it expired is only confusing, and the auth service
+	// rejects it anyway
+	if event.ExpiresAt != nil && !time.Now().Before(event.ExpiresAt.AsTime()) {
+		log.Printf("Dropping OTP for phone number %s for tenant %s: expired at %s", event.PhoneNumber, event.TenantId, event.ExpiresAt.AsTime().Format(time.RFC3339))
+		otpStats.Add("dropped_expired", 1)
+		s.reportDelivery(ctx, event, errOTPExpired)
+		return nil
 	}
 
 	// Here you would send the OTP to the user via SMS, email, etc.
 	// This part is left out for simplicity, but typically you'd integrate with an external service like Twilio.
-	log.Printf("Sending OTP to phone number %s: %s", event.PhoneNumber, event.OTPCode)
+	log.Printf("Sending OTP to phone number %s for tenant %s: %s", event.PhoneNumber, event.TenantId, event.OtpCode)
 
-	err = s.otpClient.SendOTP(ctx, event.PhoneNumber, event.OTPCode)
+	err := s.otpClient.SendOTP(ctx, event)
 
 	if err != nil {
 		log.Printf("Failed to send OTP: %v", err)
+		otpStats.Add("failed", 1)
+	} else {
+		otpStats.Add("sent", 1)
+	}
+
+	s.reportDelivery(ctx, event, err)
+
+	// A failed send is retried by the broker
+	return err
+}
+
+// reportDelivery tells the auth service whether the OTP was handed to the
+// SMS provider, so it can be recorded in the OTP ledger.
+func (s *OTPService) reportDelivery(ctx context.Context, event *eventsv1.OTPVerificationEvent, sendErr error) {
+	if event.OtpId == "" {
 		return
 	}
 
+	report := &eventsv1.OTPDeliveryEvent{
+		TenantId:    event.TenantId,
+		OtpId:       event.OtpId,
+		PhoneNumber: event.PhoneNumber,
+		Delivered:   sendErr == nil,
+		OccurredAt:  timestamppb.Now(),
+	}
+	if sendErr != nil {
+		report.Error = sendErr.Error()
+	}
+	message, err := contracts.Marshal(report)
+	if err != nil {
+		log.Printf("Failed to serialize delivery report: %v", err)
+		return
+	}
+	if err := s.messageBroker.Publish(ctx, contracts.TopicOTPDelivery, message); err != nil {
+		log.Printf("Failed to publish delivery report: %v", err)
+	}
+}
+
+func (s *OTPService) handleNotificationEvent(ctx context.Context, message []byte) error {
+	event := &eventsv1.SecurityNotificationEvent{}
+	if err := contracts.Unmarshal(ctx, message, event); err != nil {
+		log.Printf("Failed to deserialize notification event: %v", err)
+		return err
+	}
+
+	log.Printf("Sending security notification to phone number %s for tenant %s", event.PhoneNumber, event.TenantId)
+
+	if err := s.otpClient.SendNotification(ctx, event); err != nil {
+		log.Printf("Failed to send notification: %v", err)
+		return err
+	}
+	return nil
 }
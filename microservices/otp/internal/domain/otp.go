@@ -2,32 +2,12 @@ package domain
 
 import (
 	"context"
-	"encoding/json"
+	eventsv1 "midaslabs/gen/events/v1"
 )
 
-type OTPVerificationEvent struct {
-	PhoneNumber string `json:"phoneNumber"`
-	OTPCode     string `json:"otpCode"`
-}
-
-func (e *OTPVerificationEvent) Serialize() ([]byte, error) {
-	return json.Marshal(e)
-
-}
-
-func DeserializeOTPEvent(data []byte) (*OTPVerificationEvent, error) {
-	var event OTPVerificationEvent
-	if err := json.Unmarshal(data, &event); err != nil {
-		return nil, err
-	}
-	return &event, nil
-}
-
-type MessageBroker interface {
-	Publish(ctx context.Context, topic string, message []byte) error
-	Subscribe(ctx context.Context, topic string, handler func(ctx context.Context, message []byte)) error
-}
-
+// OTPServiceClient sends the texts the auth service asks for through an SMS
+// provider.
 type OTPServiceClient interface {
-	SendOTP(ctx context.Context, phone, code string) error
+	SendOTP(ctx context.Context, event *eventsv1.OTPVerificationEvent) error
+	SendNotification(ctx context.Context, event *eventsv1.SecurityNotificationEvent) error
 }
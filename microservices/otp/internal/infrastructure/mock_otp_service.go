@@ -3,6 +3,7 @@ package infrastructure
 import (
 	"context"
 	"fmt"
+	eventsv1 "midaslabs/gen/events/v1"
 
 	"github.com/twilio/twilio-go"
 )
@@ -22,7 +23,12 @@ func NewMockOTPService(AccountSID, AuthToken, ServiceSID string) *MockOTPService
 	}
 }
 
-func (s *MockOTPService) SendOTP(ctx context.Context, phone, code string) error {
-	fmt.Printf("Mock: Sent OTP %s to phone %s\n", code, phone)
+func (s *MockOTPService) SendOTP(ctx context.Context, event *eventsv1.OTPVerificationEvent) error {
+	fmt.Printf("Mock: Sent OTP %s to phone %s from %q: %s\n", event.OtpCode, event.PhoneNumber, event.Sender, event.Message)
+	return nil
+}
+
+func (s *MockOTPService) SendNotification(ctx context.Context, event *eventsv1.SecurityNotificationEvent) error {
+	fmt.Printf("Mock: Sent notification to phone %s from %q: %s\n", event.PhoneNumber, event.Sender, event.Message)
 	return nil
 }
@@ -2,32 +2,64 @@ package infrastructure
 
 import (
 	"context"
+	eventsv1 "midaslabs/gen/events/v1"
 
 	"github.com/twilio/twilio-go"
+	openapi "github.com/twilio/twilio-go/rest/api/v2010"
 	verify "github.com/twilio/twilio-go/rest/verify/v2"
 )
 
 type TwilioOTPService struct {
 	client     *twilio.RestClient
 	serviceSID string
+	sender     string
 }
 
-func NewTwilioOTPService(AccountSID, AuthToken, ServiceSID string) *TwilioOTPService {
+// NewTwilioOTPService creates a TwilioOTPService. Sender is the number or
+// alphanumeric ID texts are sent from when the tenant has none.
+func NewTwilioOTPService(AccountSID, AuthToken, ServiceSID, Sender string) *TwilioOTPService {
 	return &TwilioOTPService{
 		client: twilio.NewRestClientWithParams(twilio.ClientParams{
 			Username: AccountSID,
 			Password: AuthToken,
 		}),
 		serviceSID: ServiceSID,
+		sender:     Sender,
 	}
 }
 
-func (s *TwilioOTPService) SendOTP(ctx context.Context, phone, code string) error {
+// SendOTP texts the message the auth service rendered from the tenant's SMS
+// template, with the code it stored, through the Messages API. Events
+// without a message or sender start a Twilio Verify verification instead,
+// whose code, sender and message are chosen by the Verify service.
+func (s *TwilioOTPService) SendOTP(ctx context.Context, event *eventsv1.OTPVerificationEvent) error {
+	sender := event.Sender
+	if sender == "" {
+		sender = s.sender
+	}
+	if event.Message != "" && sender != "" {
+		params := &openapi.CreateMessageParams{}
+		params.SetTo(event.PhoneNumber)
+		params.SetFrom(sender)
+		params.SetBody(event.Message)
+		_, err := s.client.Api.CreateMessage(params)
+		return err
+	}
 
 	params := &verify.CreateVerificationParams{}
-	params.SetTo(phone)
+	params.SetTo(event.PhoneNumber)
 	params.SetChannel("sms")
 	// params.SetCustomCode(code) // Premium Feature
 	_, err := s.client.VerifyV2.CreateVerification(s.serviceSID, params)
 	return err
 }
+
+// SendNotification sends the notice as a plain SMS from the tenant's sender.
+func (s *TwilioOTPService) SendNotification(ctx context.Context, event *eventsv1.SecurityNotificationEvent) error {
+	params := &openapi.CreateMessageParams{}
+	params.SetTo(event.PhoneNumber)
+	params.SetFrom(event.Sender)
+	params.SetBody(event.Message)
+	_, err := s.client.Api.CreateMessage(params)
+	return err
+}
@@ -0,0 +1,285 @@
+// Package brokertest is a conformance suite for MessageBroker
+// implementations. Every broker in sdk runs it from its own tests, so they
+// can be swapped for one another.
+package brokertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"midaslabs/sdk/contracts"
+	"midaslabs/sdk/envelope"
+)
+
+// Broker is the MessageBroker both services depend on, plus Close.
+type Broker interface {
+	contracts.MessageBroker
+	Close() error
+}
+
+// The suite expects brokers to retry a failed message MaxRetries times, and
+// to wait at most MaxRetryDelay between attempts.
+const (
+	MaxRetries    = 2
+	MaxRetryDelay = 200 * time.Millisecond
+)
+
+// timeout bounds every wait for a delivery.
+const timeout = 10 * time.Second
+
+// Run runs the suite. newBroker is called once per test and must return a
+// broker configured with MaxRetries and delays of at most MaxRetryDelay.
+func Run(t *testing.T, newBroker func(t *testing.T) Broker) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b Broker, topic string)
+	}{
+		{"DeliversPublishedMessages", testDelivers},
+		{"QueuesUntilSubscribed", testQueuesUntilSubscribed},
+		{"CompetingConsumers", testCompetingConsumers},
+		{"RetriesFailedMessages", testRetries},
+		{"StopsRetryingAfterMaxRetries", testMaxRetries},
+		{"StopsOnCancel", testStopsOnCancel},
+		{"CarriesEnvelope", testCarriesEnvelope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBroker(t)
+			t.Cleanup(func() {
+				if err := b.Close(); err != nil {
+					t.Errorf("closing broker: %v", err)
+				}
+			})
+			tt.fn(t, b, topicFor(t))
+		})
+	}
+}
+
+// topicFor names a topic no other test or run uses, since brokers such as
+// RabbitMQ keep queues between runs.
+func topicFor(t *testing.T) string {
+	name := strings.NewReplacer("/", ".", " ", "_").Replace(t.Name())
+	return fmt.Sprintf("brokertest.%s.%d", name, time.Now().UnixNano())
+}
+
+// collector records the messages a handler received.
+type collector struct {
+	mu       sync.Mutex
+	messages []string
+	received chan struct{}
+}
+
+func newCollector() *collector {
+	return &collector{received: make(chan struct{}, 1000)}
+}
+
+func (c *collector) handle(ctx context.Context, message []byte) error {
+	c.mu.Lock()
+	c.messages = append(c.messages, string(message))
+	c.mu.Unlock()
+	c.received <- struct{}{}
+	return nil
+}
+
+// wait waits for n messages in total.
+func (c *collector) wait(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.After(timeout)
+	for i := 0; i < n; i++ {
+		select {
+		case <-c.received:
+		case <-deadline:
+			t.Fatalf("received %d of %d messages", i, n)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.messages...)
+}
+
+// quiet fails if a message arrives within d.
+func (c *collector) quiet(t *testing.T, d time.Duration) {
+	t.Helper()
+	select {
+	case <-c.received:
+		t.Fatal("received an unexpected message")
+	case <-time.After(d):
+	}
+}
+
+func subscribe(t *testing.T, b Broker, topic string, handler func(ctx context.Context, message []byte) error) context.CancelFunc {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := b.Subscribe(ctx, topic, handler); err != nil {
+		t.Fatalf("subscribing: %v", err)
+	}
+	return cancel
+}
+
+func publish(t *testing.T, b Broker, topic, message string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := b.Publish(ctx, topic, []byte(message)); err != nil {
+		t.Fatalf("publishing: %v", err)
+	}
+}
+
+func testDelivers(t *testing.T, b Broker, topic string) {
+	c := newCollector()
+	subscribe(t, b, topic, c.handle)
+
+	publish(t, b, topic, "hello")
+
+	if got := c.wait(t, 1); got[0] != "hello" {
+		t.Fatalf("received %q, want %q", got[0], "hello")
+	}
+}
+
+func testQueuesUntilSubscribed(t *testing.T, b Broker, topic string) {
+	// Declares the topic before the first publish, as services do
+	cancel := subscribe(t, b, topic, newCollector().handle)
+	cancel()
+
+	publish(t, b, topic, "first")
+	publish(t, b, topic, "second")
+
+	c := newCollector()
+	subscribe(t, b, topic, c.handle)
+
+	got := c.wait(t, 2)
+	if strings.Join(got, ",") != "first,second" {
+		t.Fatalf("received %v, want [first second]", got)
+	}
+}
+
+func testCompetingConsumers(t *testing.T, b Broker, topic string) {
+	c := newCollector()
+	for i := 0; i < 2; i++ {
+		subscribe(t, b, topic, c.handle)
+	}
+
+	const n = 20
+	for i := 0; i < n; i++ {
+		publish(t, b, topic, fmt.Sprint(i))
+	}
+
+	got := c.wait(t, n)
+	c.quiet(t, 200*time.Millisecond)
+
+	seen := map[string]bool{}
+	for _, m := range got {
+		if seen[m] {
+			t.Fatalf("message %s delivered twice", m)
+		}
+		seen[m] = true
+	}
+}
+
+func testRetries(t *testing.T, b Broker, topic string) {
+	c := newCollector()
+	var mu sync.Mutex
+	attempts := 0
+	subscribe(t, b, topic, func(ctx context.Context, message []byte) error {
+		mu.Lock()
+		attempts++
+		attempt := attempts
+		mu.Unlock()
+		if attempt == 1 {
+			return errors.New("transient failure")
+		}
+		return c.handle(ctx, message)
+	})
+
+	publish(t, b, topic, "retried")
+
+	if got := c.wait(t, 1); got[0] != "retried" {
+		t.Fatalf("received %q, want %q", got[0], "retried")
+	}
+	c.quiet(t, 2*MaxRetryDelay)
+}
+
+func testMaxRetries(t *testing.T, b Broker, topic string) {
+	attempts := make(chan struct{}, 100)
+	subscribe(t, b, topic, func(ctx context.Context, message []byte) error {
+		attempts <- struct{}{}
+		return errors.New("permanent failure")
+	})
+
+	publish(t, b, topic, "doomed")
+
+	deadline := time.After(timeout)
+	for i := 0; i < MaxRetries+1; i++ {
+		select {
+		case <-attempts:
+		case <-deadline:
+			t.Fatalf("handled %d times, want %d", i, MaxRetries+1)
+		}
+	}
+	select {
+	case <-attempts:
+		t.Fatalf("handled more than %d times", MaxRetries+1)
+	case <-time.After(4 * MaxRetryDelay):
+	}
+}
+
+func testStopsOnCancel(t *testing.T, b Broker, topic string) {
+	cancelled := newCollector()
+	cancel := subscribe(t, b, topic, cancelled.handle)
+	cancel()
+	// Give the broker time to stop the consumer
+	time.Sleep(100 * time.Millisecond)
+
+	publish(t, b, topic, "after cancel")
+
+	c := newCollector()
+	subscribe(t, b, topic, c.handle)
+
+	if got := c.wait(t, 1); got[0] != "after cancel" {
+		t.Fatalf("received %q, want %q", got[0], "after cancel")
+	}
+	cancelled.quiet(t, 100*time.Millisecond)
+}
+
+func testCarriesEnvelope(t *testing.T, b Broker, topic string) {
+	received := make(chan envelope.Metadata, 1)
+	subscribe(t, b, topic, func(ctx context.Context, message []byte) error {
+		m, _ := envelope.FromContext(ctx)
+		received <- m
+		return nil
+	})
+
+	sent := envelope.Metadata{
+		Type:        "com.midaslabs.test",
+		Version:     "2",
+		Subject:     "subject",
+		TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := b.Publish(envelope.WithPublishMetadata(ctx, sent), topic, []byte(`{}`)); err != nil {
+		t.Fatalf("publishing: %v", err)
+	}
+
+	var got envelope.Metadata
+	select {
+	case got = <-received:
+	case <-time.After(timeout):
+		t.Fatal("received no message")
+	}
+	if got.ID == "" || got.Source == "" || got.Time.IsZero() {
+		t.Errorf("missing defaults in %+v", got)
+	}
+	if got.SpecVersion != envelope.SpecVersion || got.DataContentType != "application/json" {
+		t.Errorf("got spec version %q and content type %q", got.SpecVersion, got.DataContentType)
+	}
+	if got.Type != sent.Type || got.Version != sent.Version || got.Subject != sent.Subject || got.TraceParent != sent.TraceParent {
+		t.Errorf("received %+v, want the attributes of %+v", got, sent)
+	}
+}
@@ -0,0 +1,121 @@
+// Package contracts holds what services agree on to talk over a message
+// broker: the broker interface, the topics, and the codecs of the events
+// defined in proto/events/v1.
+package contracts
+
+import (
+	"context"
+	"fmt"
+	"mime"
+
+	"midaslabs/sdk/envelope"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// MessageBroker publishes and subscribes to topics. Every broker in the sdk
+// implements it.
+type MessageBroker interface {
+	Publish(ctx context.Context, topic string, message []byte) error
+	// Subscribe calls handler for each message of the topic. A handler
+	// returning an error has the message retried.
+	Subscribe(ctx context.Context, topic string, handler func(ctx context.Context, message []byte) error) error
+}
+
+// Topics the auth and OTP services exchange events on.
+const (
+	// TopicVerification carries OTPVerificationEvent.
+	TopicVerification = "verification"
+	// TopicNotification carries SecurityNotificationEvent.
+	TopicNotification = "notification"
+	// TopicOTPDelivery carries OTPDeliveryEvent.
+	TopicOTPDelivery = "otp.delivery"
+	// TopicReverificationRequired carries ReverificationRequiredEvent.
+	TopicReverificationRequired = "user.reverification_required"
+)
+
+// Codec encodes events for the wire.
+type Codec interface {
+	// ContentType is the CloudEvents datacontenttype of the encoding.
+	ContentType() string
+	Marshal(m proto.Message) ([]byte, error)
+	Unmarshal(data []byte, m proto.Message) error
+}
+
+var (
+	// JSON is the protobuf JSON mapping, with camelCase field names. It is
+	// the default wire format.
+	JSON Codec = jsonCodec{}
+	// Binary is the protobuf wire format.
+	Binary Codec = binaryCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Marshal(m proto.Message) ([]byte, error) {
+	return protojson.Marshal(m)
+}
+
+// Unmarshal ignores unknown fields, so consumers keep reading events from
+// newer publishers.
+func (jsonCodec) Unmarshal(data []byte, m proto.Message) error {
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+}
+
+type binaryCodec struct{}
+
+func (binaryCodec) ContentType() string { return "application/protobuf" }
+
+func (binaryCodec) Marshal(m proto.Message) ([]byte, error) {
+	return proto.Marshal(m)
+}
+
+func (binaryCodec) Unmarshal(data []byte, m proto.Message) error {
+	return proto.Unmarshal(data, m)
+}
+
+// CodecFor returns the codec of a datacontenttype. Events without one are
+// JSON.
+func CodecFor(contentType string) (Codec, error) {
+	if contentType == "" {
+		return JSON, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("contracts: content type %q: %w", contentType, err)
+	}
+	switch mediaType {
+	case "application/json":
+		return JSON, nil
+	case "application/protobuf", "application/x-protobuf":
+		return Binary, nil
+	}
+	return nil, fmt.Errorf("contracts: unsupported content type %q", contentType)
+}
+
+// Marshal encodes an event in the default wire format, JSON.
+func Marshal(m proto.Message) ([]byte, error) {
+	return JSON.Marshal(m)
+}
+
+// Unmarshal decodes the event being handled with the codec of the
+// datacontenttype in its envelope.
+func Unmarshal(ctx context.Context, data []byte, m proto.Message) error {
+	meta, _ := envelope.FromContext(ctx)
+	codec, err := CodecFor(meta.DataContentType)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(data, m)
+}
+
+// WithCodec returns a context publishing with codec's datacontenttype, to
+// pass to Publish along with an event it encoded. It replaces attributes set
+// with envelope.WithPublishMetadata; set DataContentType there instead when
+// setting others.
+func WithCodec(ctx context.Context, codec Codec) context.Context {
+	return envelope.WithPublishMetadata(ctx, envelope.Metadata{DataContentType: codec.ContentType()})
+}
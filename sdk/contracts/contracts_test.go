@@ -0,0 +1,205 @@
+package contracts
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	eventsv1 "midaslabs/gen/events/v1"
+	"midaslabs/sdk/envelope"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// -update records the goldens of cases that have none. Existing goldens are
+// payloads already on the wire and are never rewritten.
+var update = flag.Bool("update", false, "record missing golden payloads")
+
+var occurredAt = timestamppb.New(time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC))
+
+// goldens are the events whose recorded payloads, testdata/<name>.json and
+// testdata/<name>.binpb, must keep decoding to the same values.
+var goldens = []struct {
+	name  string
+	event proto.Message
+}{
+	{"otp_verification", &eventsv1.OTPVerificationEvent{
+		TenantId:    "acme",
+		OtpId:       "5f2b8e0c4a1d9e7f3b6c2a10",
+		PhoneNumber: "+15550100",
+		OtpCode:     "4f9a21",
+		Sender:      "ACME",
+		Message:     "Your ACME code is 4f9a21",
+	}},
+	{"otp_verification_expiring", &eventsv1.OTPVerificationEvent{
+		TenantId:    "acme",
+		OtpId:       "5f2b8e0c4a1d9e7f3b6c2a10",
+		PhoneNumber: "+15550100",
+		OtpCode:     "4f9a21",
+		Sender:      "ACME",
+		Message:     "Your ACME code is 4f9a21",
+		ExpiresAt:   timestamppb.New(time.Date(2024, 5, 1, 12, 5, 0, 0, time.UTC)),
+	}},
+	{"security_notification", &eventsv1.SecurityNotificationEvent{
+		TenantId:    "acme",
+		PhoneNumber: "+15550100",
+		Sender:      "ACME",
+		Message:     "ACME: new sign-in from Firefox on Linux. Not you? Reject it with code K3J5QW.",
+		AlertId:     "K3J5QW",
+	}},
+	{"otp_delivery", &eventsv1.OTPDeliveryEvent{
+		TenantId:    "acme",
+		OtpId:       "5f2b8e0c4a1d9e7f3b6c2a10",
+		PhoneNumber: "+15550100",
+		Error:       "twilio: 21614 not a mobile number",
+		OccurredAt:  occurredAt,
+	}},
+	{"user_event", &eventsv1.UserEvent{
+		TenantId:    "acme",
+		PhoneNumber: "+15550100",
+		Status:      "suspended",
+		Verified:    true,
+		Reason:      "chargeback",
+		Actor:       "+15550199",
+		LastLoginAt: occurredAt,
+		OccurredAt:  occurredAt,
+	}},
+	{"reverification_required", &eventsv1.ReverificationRequiredEvent{
+		TenantId:    "acme",
+		PhoneNumber: "+15550100",
+		Reason:      "dormant",
+		LastLoginAt: occurredAt,
+		OccurredAt:  occurredAt,
+	}},
+}
+
+func TestGoldens(t *testing.T) {
+	for _, tt := range goldens {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, codec := range []struct {
+				Codec
+				ext string
+			}{{JSON, ".json"}, {Binary, ".binpb"}} {
+				path := filepath.Join("testdata", tt.name+codec.ext)
+				golden, err := os.ReadFile(path)
+				if errors.Is(err, fs.ErrNotExist) && *update {
+					golden = record(t, path, codec, tt.event)
+				} else if err != nil {
+					t.Fatal(err)
+				}
+
+				got := tt.event.ProtoReflect().New().Interface()
+				if err := codec.Unmarshal(golden, got); err != nil {
+					t.Fatalf("%s: %v", path, err)
+				}
+				if !proto.Equal(got, tt.event) {
+					t.Errorf("%s decodes to %v, want %v", path, got, tt.event)
+				}
+
+				// protojson output is not stable byte for byte, so only the
+				// binary encoding is compared
+				if codec.Codec == Binary {
+					encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(got)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if string(encoded) != string(golden) {
+						t.Errorf("%s re-encodes differently", path)
+					}
+				}
+			}
+		})
+	}
+}
+
+func record(t *testing.T, path string, codec Codec, event proto.Message) []byte {
+	t.Helper()
+
+	data, err := codec.Marshal(event)
+	if codec == Binary {
+		data, err = proto.MarshalOptions{Deterministic: true}.Marshal(event)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("recorded %s", path)
+	return data
+}
+
+// TestLegacyPayloads decodes the JSON the services published before the
+// contracts existed, which may still be queued.
+func TestLegacyPayloads(t *testing.T) {
+	tests := []struct {
+		file string
+		want proto.Message
+	}{
+		{"otp_verification.json", goldens[0].event},
+		{"security_notification.json", goldens[2].event},
+		// encoding/json wrote times with the local offset
+		{"otp_delivery.json", goldens[3].event},
+		{"user_event.json", goldens[4].event},
+		// Written by the retention purge with jsonb_build_object
+		{"user_deleted.json", &eventsv1.UserEvent{
+			TenantId:    "acme",
+			PhoneNumber: "+15550100",
+			Status:      "active",
+			Reason:      "unverified",
+			OccurredAt:  timestamppb.New(time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)),
+		}},
+		{"reverification_required.json", goldens[5].event},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "legacy", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := tt.want.ProtoReflect().New().Interface()
+			if err := JSON.Unmarshal(data, got); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("decodes to %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalUsesContentType(t *testing.T) {
+	want := goldens[0].event
+	binary, err := Binary.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := envelope.NewContext(context.Background(), TopicVerification, envelope.Metadata{DataContentType: "application/protobuf"})
+	got := &eventsv1.OTPVerificationEvent{}
+	if err := Unmarshal(ctx, binary, got); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Without an envelope, events are JSON
+	if err := Unmarshal(context.Background(), []byte(`{"tenantId":"acme","unknownField":1}`), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.TenantId != "acme" {
+		t.Errorf("tenantId = %q, want acme", got.TenantId)
+	}
+
+	ctx = envelope.NewContext(context.Background(), TopicVerification, envelope.Metadata{DataContentType: "text/xml"})
+	if err := Unmarshal(ctx, binary, got); err == nil {
+		t.Error("unsupported content type decoded")
+	}
+}
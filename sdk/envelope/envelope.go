@@ -0,0 +1,237 @@
+// Package envelope describes the messages the services exchange with the
+// CloudEvents 1.0 context attributes. Brokers carry the attributes in binary
+// mode: the payload is the event data, and each attribute travels in a
+// message header of its own.
+package envelope
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SpecVersion is the CloudEvents version the attributes follow.
+const SpecVersion = "1.0"
+
+// DefaultVersion is the schema version of events published without one.
+const DefaultVersion = "1"
+
+// DefaultSource is the source of events published by a broker configured
+// without one.
+const DefaultSource = "midaslabs"
+
+// Metadata holds the CloudEvents attributes of a message.
+type Metadata struct {
+	ID          string
+	Source      string
+	Type        string
+	SpecVersion string
+	// Version is the version of the data's schema, carried in the
+	// schemaversion extension, so that several versions of an event can
+	// share a topic.
+	Version         string
+	Subject         string
+	Time            time.Time
+	DataContentType string
+	DataSchema      string
+	// TraceParent and TraceState carry the W3C trace context of the
+	// distributed tracing extension.
+	TraceParent string
+	TraceState  string
+	// Expiry, carried in the expiry extension, is when the event stops being
+	// worth handling. Brokers with per-message TTLs discard the message
+	// after it.
+	Expiry time.Time
+}
+
+// Complete fills in the attributes a publisher left out: a random ID, the
+// current time, the topic as type, the given source, DefaultVersion and JSON
+// data.
+func (m Metadata) Complete(topic, source string) Metadata {
+	if m.ID == "" {
+		m.ID = newID()
+	}
+	if m.Source == "" {
+		m.Source = source
+	}
+	if m.Type == "" {
+		m.Type = topic
+	}
+	if m.SpecVersion == "" {
+		m.SpecVersion = SpecVersion
+	}
+	if m.Version == "" {
+		m.Version = DefaultVersion
+	}
+	if m.Time.IsZero() {
+		m.Time = time.Now().UTC()
+	}
+	if m.DataContentType == "" {
+		m.DataContentType = "application/json"
+	}
+	return m
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Headers maps the attributes onto headers named with prefix, such as
+// "cloudEvents:" for AMQP or "ce-" for NATS. The data content type is left
+// to the transport's own content type, and empty attributes are omitted.
+func (m Metadata) Headers(prefix string) map[string]string {
+	headers := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			headers[prefix+name] = value
+		}
+	}
+	set("id", m.ID)
+	set("source", m.Source)
+	set("type", m.Type)
+	set("specversion", m.SpecVersion)
+	set("schemaversion", m.Version)
+	set("subject", m.Subject)
+	if !m.Time.IsZero() {
+		set("time", m.Time.Format(time.RFC3339Nano))
+	}
+	set("dataschema", m.DataSchema)
+	set("traceparent", m.TraceParent)
+	set("tracestate", m.TraceState)
+	if !m.Expiry.IsZero() {
+		set("expiry", m.Expiry.Format(time.RFC3339Nano))
+	}
+	return headers
+}
+
+// FromHeaders reads the attributes back from headers named with prefix.
+// Headers other than the attributes are ignored, and a malformed time or
+// expiry is left zero.
+func FromHeaders(prefix string, headers map[string]string) Metadata {
+	var m Metadata
+	for name, value := range headers {
+		attr, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		switch attr {
+		case "id":
+			m.ID = value
+		case "source":
+			m.Source = value
+		case "type":
+			m.Type = value
+		case "specversion":
+			m.SpecVersion = value
+		case "schemaversion":
+			m.Version = value
+		case "subject":
+			m.Subject = value
+		case "time":
+			m.Time, _ = time.Parse(time.RFC3339Nano, value)
+		case "dataschema":
+			m.DataSchema = value
+		case "traceparent":
+			m.TraceParent = value
+		case "tracestate":
+			m.TraceState = value
+		case "expiry":
+			m.Expiry, _ = time.Parse(time.RFC3339Nano, value)
+		}
+	}
+	return m
+}
+
+type receivedKey struct{}
+
+// received is the message being handled and the topic it was received on.
+type received struct {
+	topic string
+	meta  Metadata
+}
+
+type publishKey struct{}
+
+// NewContext returns a copy of ctx carrying the metadata of the message
+// being handled and the topic it was received on. Brokers call it before
+// invoking a handler.
+func NewContext(ctx context.Context, topic string, m Metadata) context.Context {
+	return context.WithValue(ctx, receivedKey{}, received{topic: topic, meta: m})
+}
+
+// FromContext returns the metadata of the message being handled.
+func FromContext(ctx context.Context) (Metadata, bool) {
+	r, ok := ctx.Value(receivedKey{}).(received)
+	return r.meta, ok
+}
+
+// WithPublishMetadata returns a copy of ctx whose publishes carry m.
+func WithPublishMetadata(ctx context.Context, m Metadata) context.Context {
+	return context.WithValue(ctx, publishKey{}, m)
+}
+
+// PublishMetadata returns the metadata to publish a message on topic with:
+// the attributes set through WithPublishMetadata, completed, and the trace
+// context of the message being handled unless set.
+func PublishMetadata(ctx context.Context, topic, source string) Metadata {
+	m, _ := ctx.Value(publishKey{}).(Metadata)
+	if received, ok := FromContext(ctx); ok && m.TraceParent == "" {
+		m.TraceParent, m.TraceState = received.TraceParent, received.TraceState
+	}
+	return m.Complete(topic, source)
+}
+
+// Router dispatches a topic's messages to the handler registered for their
+// type and schema version, so that several versions can share the topic.
+type Router struct {
+	handlers map[routeKey]func(ctx context.Context, message []byte) error
+	fallback func(ctx context.Context, message []byte) error
+}
+
+type routeKey struct {
+	eventType string
+	version   string
+}
+
+// NewRouter returns a router sending messages matching no registered
+// handler to fallback. Without a fallback they fail.
+func NewRouter(fallback func(ctx context.Context, message []byte) error) *Router {
+	return &Router{
+		handlers: make(map[routeKey]func(ctx context.Context, message []byte) error),
+		fallback: fallback,
+	}
+}
+
+// Handle registers the handler of one version of an event type.
+func (r *Router) Handle(eventType, version string, handler func(ctx context.Context, message []byte) error) {
+	r.handlers[routeKey{eventType, version}] = handler
+}
+
+// Dispatch is the handler to subscribe with. Messages published without
+// metadata, such as ones queued before envelopes were introduced, count as
+// DefaultVersion of the type named after the topic they were received on,
+// which is the type publishers default to.
+func (r *Router) Dispatch(ctx context.Context, message []byte) error {
+	received, _ := ctx.Value(receivedKey{}).(received)
+	eventType, version := received.meta.Type, received.meta.Version
+	if eventType == "" {
+		eventType = received.topic
+	}
+	if version == "" {
+		version = DefaultVersion
+	}
+	if handler, ok := r.handlers[routeKey{eventType, version}]; ok {
+		return handler(ctx, message)
+	}
+	if r.fallback == nil {
+		return fmt.Errorf("no handler for %s version %s", eventType, version)
+	}
+	return r.fallback(ctx, message)
+}
@@ -0,0 +1,314 @@
+// Package memory provides an in-process MessageBroker for tests and local
+// development. It follows the semantics of sdk/rabbitmq within one process:
+// messages wait in a queue per topic until a subscriber takes them, each
+// message goes to one of the topic's subscribers, and messages whose handler
+// fails are retried with backoff, then parked as dead letters. Messages keep
+// their envelope metadata.
+package memory
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"midaslabs/sdk/envelope"
+)
+
+// ErrBrokerClosed is returned by Publish and Subscribe after Close.
+var ErrBrokerClosed = errors.New("memory: broker closed")
+
+// RetryPolicy bounds the redelivery of messages whose handler failed.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  time.Second,
+	MaxDelay:   time.Minute,
+}
+
+// Delay returns how long the given retry, counted from 1, waits.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Option configures a Broker.
+type Option func(*Broker)
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(b *Broker) {
+		b.retry = policy
+	}
+}
+
+// WithSource sets the CloudEvents source of the messages the broker
+// publishes instead of envelope.DefaultSource.
+func WithSource(source string) Option {
+	return func(b *Broker) {
+		b.source = source
+	}
+}
+
+// DeadLetter is a message that kept failing.
+type DeadLetter struct {
+	Meta      envelope.Metadata
+	Retries   int
+	LastError string
+	Body      []byte
+}
+
+type Broker struct {
+	retry  RetryPolicy
+	source string
+	done   chan struct{}
+
+	mu          sync.Mutex
+	queues      map[string]*queue
+	deadLetters map[string][]DeadLetter
+	closed      bool
+
+	inflight sync.WaitGroup
+}
+
+type delivery struct {
+	meta    envelope.Metadata
+	body    []byte
+	retries int
+}
+
+func NewBroker(opts ...Option) *Broker {
+	b := &Broker{
+		retry:       DefaultRetryPolicy,
+		source:      envelope.DefaultSource,
+		done:        make(chan struct{}),
+		queues:      make(map[string]*queue),
+		deadLetters: make(map[string][]DeadLetter),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// queue returns the topic's queue, creating it on first use.
+func (b *Broker) queue(topic string) (*queue, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+	q, ok := b.queues[topic]
+	if !ok {
+		q = newQueue()
+		b.queues[topic] = q
+		go q.run(b.done)
+	}
+	return q, nil
+}
+
+// Publish queues the message on the topic, whether or not anyone subscribes.
+func (b *Broker) Publish(ctx context.Context, topic string, message []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	q, err := b.queue(topic)
+	if err != nil {
+		return err
+	}
+	q.push(delivery{
+		meta: envelope.PublishMetadata(ctx, topic, b.source),
+		body: append([]byte(nil), message...),
+	})
+	return nil
+}
+
+// Subscribe consumes the topic's queue until ctx is cancelled. A message
+// being handled when ctx is cancelled is finished; its handler's context is
+// not cancelled with the subscription.
+func (b *Broker) Subscribe(ctx context.Context, topic string, handler func(ctx context.Context, message []byte) error) error {
+	q, err := b.queue(topic)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case d := <-q.out:
+				if ctx.Err() != nil || !b.begin() {
+					// Cancelled or closed while waiting; leave the message to
+					// another subscriber
+					q.pushFront(d)
+					return
+				}
+				b.handle(ctx, topic, q, d, handler)
+			case <-ctx.Done():
+				return
+			case <-b.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// begin counts a message as in flight, unless the broker is closed. Close
+// waits for the messages counted before it.
+func (b *Broker) begin() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return false
+	}
+	b.inflight.Add(1)
+	return true
+}
+
+// handle runs the handler and schedules a retry, or parks the message, when
+// it fails. The message must have been counted in flight by begin.
+func (b *Broker) handle(ctx context.Context, topic string, q *queue, d delivery, handler func(ctx context.Context, message []byte) error) {
+	defer b.inflight.Done()
+
+	err := handler(envelope.NewContext(context.WithoutCancel(ctx), topic, d.meta), d.body)
+	if err == nil {
+		return
+	}
+
+	d.retries++
+	if d.retries > b.retry.MaxRetries {
+		log.Printf("Parking message on %s after %d failed attempts: %v", topic, d.retries, err)
+		b.mu.Lock()
+		b.deadLetters[topic] = append(b.deadLetters[topic], DeadLetter{
+			Meta:      d.meta,
+			Retries:   d.retries,
+			LastError: err.Error(),
+			Body:      d.body,
+		})
+		b.mu.Unlock()
+		return
+	}
+	time.AfterFunc(b.retry.Delay(d.retries), func() {
+		q.push(d)
+	})
+}
+
+// DeadLetters returns the messages parked for the topic.
+func (b *Broker) DeadLetters(topic string) []DeadLetter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]DeadLetter(nil), b.deadLetters[topic]...)
+}
+
+// ReplayDeadLetters moves the topic's parked messages back onto the topic
+// with a fresh retry budget, and returns how many it moved.
+func (b *Broker) ReplayDeadLetters(topic string) (int, error) {
+	q, err := b.queue(topic)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	letters := b.deadLetters[topic]
+	delete(b.deadLetters, topic)
+	b.mu.Unlock()
+
+	for _, letter := range letters {
+		q.push(delivery{meta: letter.Meta, body: letter.Body})
+	}
+	return len(letters), nil
+}
+
+// Close stops every subscription and waits for in-flight messages to be
+// handled. Queued messages are discarded.
+func (b *Broker) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	b.mu.Unlock()
+
+	b.inflight.Wait()
+	return nil
+}
+
+// queue holds a topic's pending messages and hands them, one at a time, to
+// whichever subscriber receives from out first.
+type queue struct {
+	mu      sync.Mutex
+	pending []delivery
+	wake    chan struct{}
+	out     chan delivery
+}
+
+func newQueue() *queue {
+	return &queue{
+		wake: make(chan struct{}, 1),
+		out:  make(chan delivery),
+	}
+}
+
+func (q *queue) push(d delivery) {
+	q.mu.Lock()
+	q.pending = append(q.pending, d)
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *queue) pushFront(d delivery) {
+	q.mu.Lock()
+	q.pending = append([]delivery{d}, q.pending...)
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run offers the oldest pending message on out until done is closed.
+func (q *queue) run(done <-chan struct{}) {
+	for {
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.mu.Unlock()
+			select {
+			case <-q.wake:
+				continue
+			case <-done:
+				return
+			}
+		}
+		d := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		select {
+		case q.out <- d:
+		case <-q.wake:
+			// Offer it again along with whatever was pushed
+			q.mu.Lock()
+			q.pending = append([]delivery{d}, q.pending...)
+			q.mu.Unlock()
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"midaslabs/sdk/brokertest"
+	"midaslabs/sdk/envelope"
+)
+
+func TestConformance(t *testing.T) {
+	brokertest.Run(t, func(t *testing.T) brokertest.Broker {
+		return NewBroker(WithRetryPolicy(RetryPolicy{
+			MaxRetries: brokertest.MaxRetries,
+			BaseDelay:  10 * time.Millisecond,
+			MaxDelay:   brokertest.MaxRetryDelay,
+		}))
+	})
+}
+
+// TestRoutesMessagesWithoutEnvelope covers messages queued before envelopes
+// were introduced: they carry no type or version, and must still reach the
+// topic's version 1 handler rather than fail into the dead letters.
+func TestRoutesMessagesWithoutEnvelope(t *testing.T) {
+	const topic = "verification"
+	b := NewBroker(WithRetryPolicy(RetryPolicy{MaxRetries: 0, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+	defer b.Close()
+
+	// Bypass Publish, which always adds an envelope
+	q, err := b.queue(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.push(delivery{body: []byte(`{"otpCode":"123456"}`)})
+
+	got := make(chan string, 1)
+	router := envelope.NewRouter(nil)
+	router.Handle(topic, envelope.DefaultVersion, func(ctx context.Context, message []byte) error {
+		got <- string(message)
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := b.Subscribe(ctx, topic, router.Dispatch); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case message := <-got:
+		if message != `{"otpCode":"123456"}` {
+			t.Errorf("got %s", message)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("message was not routed; dead letters: %v", b.DeadLetters(topic))
+	}
+}
+
+// TestCloseWaitsForHandlers checks that no handler starts after Close has
+// returned, and that Close waits for those already running.
+func TestCloseWaitsForHandlers(t *testing.T) {
+	const topic = "notification"
+	for range 20 {
+		b := NewBroker()
+		var closed atomic.Bool
+		var running, late atomic.Int32
+		handler := func(ctx context.Context, message []byte) error {
+			if closed.Load() {
+				late.Add(1)
+			}
+			running.Add(1)
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return nil
+		}
+		for range 4 {
+			if err := b.Subscribe(context.Background(), topic, handler); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for range 50 {
+			if err := b.Publish(context.Background(), topic, []byte("{}")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		time.Sleep(2 * time.Millisecond)
+		b.Close()
+		closed.Store(true)
+		if n := running.Load(); n != 0 {
+			t.Fatalf("%d handlers still running after Close returned", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+		if n := late.Load(); n != 0 {
+			t.Fatalf("%d handlers started after Close returned", n)
+		}
+	}
+}
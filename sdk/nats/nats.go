@@ -0,0 +1,311 @@
+// Package nats implements the services' MessageBroker on NATS JetStream.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"midaslabs/sdk/envelope"
+
+	natsio "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// consumerName is the durable consumer every subscriber of a topic shares,
+// so that each message goes to one of them.
+const consumerName = "workers"
+
+// headerPrefix names the headers carrying CloudEvents attributes, following
+// the NATS binding.
+const headerPrefix = "ce-"
+
+// Headers describing a dead-lettered message's failures.
+const (
+	headerRetries   = "X-Retries"
+	headerLastError = "X-Last-Error"
+)
+
+// RetryPolicy bounds the redelivery of messages whose handler failed.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  time.Second,
+	MaxDelay:   time.Minute,
+}
+
+// Delay returns how long the given retry, counted from 1, waits.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Option configures a JetStreamBroker.
+type Option func(*JetStreamBroker)
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(b *JetStreamBroker) {
+		b.retry = policy
+	}
+}
+
+// WithSource sets the CloudEvents source of the messages the broker
+// publishes instead of envelope.DefaultSource.
+func WithSource(source string) Option {
+	return func(b *JetStreamBroker) {
+		b.source = source
+	}
+}
+
+// WithAckWait sets how long a handler may take before JetStream redelivers
+// its message. It defaults to 30 seconds.
+func WithAckWait(wait time.Duration) Option {
+	return func(b *JetStreamBroker) {
+		b.ackWait = wait
+	}
+}
+
+// DeadLetterTopic names the subject parking a topic's failed messages.
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// JetStreamBroker keeps each topic in a work-queue stream of its own, so
+// messages wait until a subscriber takes them. A topic's subscribers share a
+// durable consumer. A handler error naks the message with a delay growing
+// with each delivery; once the retries are exhausted the message is copied to
+// the topic's dead-letter stream and terminated. The client reconnects on its
+// own after outages.
+type JetStreamBroker struct {
+	conn    *natsio.Conn
+	js      jetstream.JetStream
+	retry   RetryPolicy
+	ackWait time.Duration
+	source  string
+	closed  chan struct{}
+
+	mu      sync.Mutex
+	streams map[string]jetstream.Stream
+}
+
+func NewJetStreamBroker(url string, opts ...Option) (*JetStreamBroker, error) {
+	b := &JetStreamBroker{
+		retry:   DefaultRetryPolicy,
+		ackWait: 30 * time.Second,
+		source:  envelope.DefaultSource,
+		closed:  make(chan struct{}),
+		streams: make(map[string]jetstream.Stream),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	conn, err := natsio.Connect(url,
+		natsio.MaxReconnects(-1),
+		natsio.DisconnectErrHandler(func(_ *natsio.Conn, err error) {
+			log.Printf("NATS connection lost: %v", err)
+		}),
+		natsio.ReconnectHandler(func(*natsio.Conn) {
+			log.Println("Reconnected to NATS")
+		}),
+		natsio.ClosedHandler(func(*natsio.Conn) {
+			close(b.closed)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	b.conn, b.js = conn, js
+	return b, nil
+}
+
+// streamName derives a stream name from a topic, as stream names cannot
+// contain dots.
+func streamName(topic string) string {
+	return strings.ReplaceAll(topic, ".", "_")
+}
+
+// stream creates the topic's stream on first use.
+func (b *JetStreamBroker) stream(ctx context.Context, topic string) (jetstream.Stream, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if s, ok := b.streams[topic]; ok {
+		return s, nil
+	}
+	s, err := b.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      streamName(topic),
+		Subjects:  []string{topic},
+		Retention: jetstream.WorkQueuePolicy,
+		Storage:   jetstream.FileStorage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating stream for %s: %w", topic, err)
+	}
+	b.streams[topic] = s
+	return s, nil
+}
+
+// Publish stores the message in the topic's stream and returns once
+// JetStream acknowledged it. The envelope's attributes travel in headers.
+func (b *JetStreamBroker) Publish(ctx context.Context, topic string, message []byte) error {
+	if _, err := b.stream(ctx, topic); err != nil {
+		return err
+	}
+
+	meta := envelope.PublishMetadata(ctx, topic, b.source)
+	msg := natsio.NewMsg(topic)
+	msg.Data = message
+	for k, v := range meta.Headers(headerPrefix) {
+		msg.Header.Set(k, v)
+	}
+	msg.Header.Set("content-type", meta.DataContentType)
+	// Lets JetStream drop duplicates of a message published twice
+	msg.Header.Set(natsio.MsgIdHdr, meta.ID)
+
+	_, err := b.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// decode reads the attributes of a message.
+func decode(msg jetstream.Msg) envelope.Metadata {
+	headers := make(map[string]string, len(msg.Headers()))
+	for k := range msg.Headers() {
+		headers[k] = msg.Headers().Get(k)
+	}
+	m := envelope.FromHeaders(headerPrefix, headers)
+	m.DataContentType = msg.Headers().Get("content-type")
+	return m
+}
+
+// Subscribe consumes the topic until ctx is cancelled. A message being
+// handled when ctx is cancelled is finished; prefetched ones are returned.
+func (b *JetStreamBroker) Subscribe(ctx context.Context, topic string, handler func(ctx context.Context, message []byte) error) error {
+	s, err := b.stream(ctx, topic)
+	if err != nil {
+		return err
+	}
+	// The dead-letter stream must exist before a message is parked
+	if _, err := b.stream(ctx, DeadLetterTopic(topic)); err != nil {
+		return err
+	}
+
+	consumer, err := s.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:   consumerName,
+		AckPolicy: jetstream.AckExplicitPolicy,
+		AckWait:   b.ackWait,
+		// Backstop for messages whose handler never answered
+		MaxDeliver: b.retry.MaxRetries + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("creating consumer for %s: %w", topic, err)
+	}
+
+	consumeCtx, err := consumer.Consume(func(msg jetstream.Msg) {
+		b.handle(ctx, topic, msg, handler)
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			consumeCtx.Drain()
+		case <-b.closed:
+		}
+	}()
+	return nil
+}
+
+// handle runs the handler and acks, naks or dead-letters the message.
+func (b *JetStreamBroker) handle(ctx context.Context, topic string, msg jetstream.Msg, handler func(ctx context.Context, message []byte) error) {
+	if ctx.Err() != nil {
+		// Cancelled before it started; leave it to another subscriber
+		if err := msg.Nak(); err != nil {
+			log.Printf("Failed to nak message: %v", err)
+		}
+		return
+	}
+
+	handlerErr := handler(envelope.NewContext(context.WithoutCancel(ctx), topic, decode(msg)), msg.Data())
+	if handlerErr == nil {
+		if err := msg.Ack(); err != nil {
+			log.Printf("Failed to acknowledge message: %v", err)
+		}
+		return
+	}
+
+	meta, err := msg.Metadata()
+	if err != nil {
+		log.Printf("Failed to read message metadata: %v", err)
+		return
+	}
+	attempts := int(meta.NumDelivered)
+	if attempts <= b.retry.MaxRetries {
+		if err := msg.NakWithDelay(b.retry.Delay(attempts)); err != nil {
+			log.Printf("Failed to nak message: %v", err)
+		}
+		return
+	}
+
+	if err := b.deadLetter(topic, msg, attempts, handlerErr); err != nil {
+		// Leave it to JetStream to redeliver after the ack wait
+		log.Printf("Failed to park message on %s: %v", DeadLetterTopic(topic), err)
+		return
+	}
+	if err := msg.Term(); err != nil {
+		log.Printf("Failed to terminate message: %v", err)
+	}
+}
+
+func (b *JetStreamBroker) deadLetter(topic string, msg jetstream.Msg, attempts int, handlerErr error) error {
+	log.Printf("Parking message on %s after %d failed attempts: %v", DeadLetterTopic(topic), attempts, handlerErr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	dead := natsio.NewMsg(DeadLetterTopic(topic))
+	dead.Data = msg.Data()
+	for k, v := range msg.Headers() {
+		if k != natsio.MsgIdHdr {
+			dead.Header[k] = v
+		}
+	}
+	dead.Header.Set(headerRetries, fmt.Sprint(attempts-1))
+	dead.Header.Set(headerLastError, handlerErr.Error())
+	_, err := b.js.PublishMsg(ctx, dead)
+	return err
+}
+
+// Close drains every subscription, letting in-flight messages finish, and
+// closes the connection.
+func (b *JetStreamBroker) Close() error {
+	if err := b.conn.Drain(); err != nil {
+		if errors.Is(err, natsio.ErrConnectionClosed) {
+			return nil
+		}
+		return err
+	}
+	<-b.closed
+	return nil
+}
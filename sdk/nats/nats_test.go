@@ -0,0 +1,66 @@
+package nats
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"midaslabs/sdk/brokertest"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// serverURL is the client URL of the embedded JetStream server.
+var serverURL string
+
+// TestMain runs the suite against an in-process JetStream server storing its
+// streams in a temporary directory.
+func TestMain(m *testing.M) {
+	os.Exit(runWithServer(m))
+}
+
+func runWithServer(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "nats-test-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating jetstream directory: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  dir,
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "creating nats server: %v\n", err)
+		return 1
+	}
+	srv.Start()
+	defer srv.Shutdown()
+	if !srv.ReadyForConnections(10 * time.Second) {
+		fmt.Fprintln(os.Stderr, "nats server did not start")
+		return 1
+	}
+
+	serverURL = srv.ClientURL()
+	return m.Run()
+}
+
+func TestConformance(t *testing.T) {
+	brokertest.Run(t, func(t *testing.T) brokertest.Broker {
+		b, err := NewJetStreamBroker(serverURL, WithRetryPolicy(RetryPolicy{
+			MaxRetries: brokertest.MaxRetries,
+			BaseDelay:  10 * time.Millisecond,
+			MaxDelay:   brokertest.MaxRetryDelay,
+		}))
+		if err != nil {
+			t.Fatalf("connecting to nats: %v", err)
+		}
+		return b
+	})
+}
@@ -0,0 +1,450 @@
+// Package postgres implements the services' MessageBroker on a Postgres
+// table, for deployments that run Postgres but no message broker. The
+// database must be migrated with db/auth/migrations, and opened with the pgx
+// driver, whose LISTEN/NOTIFY support wakes subscribers.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"midaslabs/sdk/envelope"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// channel is notified with the topic of every published message.
+const channel = "broker_messages"
+
+// ErrBrokerClosed is returned by Subscribe after Close.
+var ErrBrokerClosed = errors.New("postgres: broker closed")
+
+// RetryPolicy bounds the redelivery of messages whose handler failed.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  time.Second,
+	MaxDelay:   time.Minute,
+}
+
+// Delay returns how long the given retry, counted from 1, waits.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// Option configures a PostgresBroker.
+type Option func(*PostgresBroker)
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(b *PostgresBroker) {
+		b.retry = policy
+	}
+}
+
+// WithVisibilityTimeout sets how long a claimed message stays hidden from
+// other subscribers. It also bounds each handler call, so that a message is
+// only redelivered once its subscriber gave up on it or died. It defaults to
+// 30 seconds.
+func WithVisibilityTimeout(timeout time.Duration) Option {
+	return func(b *PostgresBroker) {
+		b.visibility = timeout
+	}
+}
+
+// WithSource sets the CloudEvents source of the messages the broker
+// publishes instead of envelope.DefaultSource.
+func WithSource(source string) Option {
+	return func(b *PostgresBroker) {
+		b.source = source
+	}
+}
+
+// WithPollInterval sets how often idle subscribers look for messages whose
+// retry delay or visibility timeout ran out, as those raise no notification.
+// It defaults to one second.
+func WithPollInterval(interval time.Duration) Option {
+	return func(b *PostgresBroker) {
+		b.pollInterval = interval
+	}
+}
+
+// PostgresBroker queues messages in the broker_messages table. Subscribers
+// claim the oldest visible message of their topic with FOR UPDATE SKIP
+// LOCKED, which hides it for the visibility timeout, and delete it once
+// handled. A failed message becomes visible again after its retry delay, and
+// is dead-lettered in place once its retries are exhausted. Publishing
+// notifies the broker_messages channel, which wakes idle subscribers.
+type PostgresBroker struct {
+	db           *sqlx.DB
+	retry        RetryPolicy
+	visibility   time.Duration
+	pollInterval time.Duration
+	source       string
+
+	done        chan struct{}
+	subscribers sync.WaitGroup
+
+	mu     sync.Mutex
+	wakers map[string]map[chan struct{}]struct{}
+	closed bool
+}
+
+func NewPostgresBroker(db *sqlx.DB, opts ...Option) *PostgresBroker {
+	b := &PostgresBroker{
+		db:           db,
+		retry:        DefaultRetryPolicy,
+		visibility:   30 * time.Second,
+		pollInterval: time.Second,
+		source:       envelope.DefaultSource,
+		done:         make(chan struct{}),
+		wakers:       make(map[string]map[chan struct{}]struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	b.subscribers.Add(1)
+	go b.listen()
+	return b
+}
+
+// Publish queues the message with its envelope's attributes and notifies
+// subscribers. It runs on the broker's own connection pool and commits at
+// once; it never joins a transaction of the caller, so changes that must be
+// published atomically go through the service's outbox.
+func (b *PostgresBroker) Publish(ctx context.Context, topic string, message []byte) error {
+	meta := envelope.PublishMetadata(ctx, topic, b.source)
+	headers := meta.Headers("")
+	headers["datacontenttype"] = meta.DataContentType
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+
+	const query = `
+		WITH queued AS (
+			INSERT INTO broker_messages (topic, payload, headers) VALUES ($1, $2, $3)
+			RETURNING topic
+		)
+		SELECT pg_notify($4, topic) FROM queued`
+	_, err = b.db.ExecContext(ctx, query, topic, message, encoded, channel)
+	return err
+}
+
+// decode reads the attributes stored with a message.
+func decode(encoded []byte) envelope.Metadata {
+	var headers map[string]string
+	if err := json.Unmarshal(encoded, &headers); err != nil {
+		log.Printf("Failed to decode message headers: %v", err)
+	}
+	m := envelope.FromHeaders("", headers)
+	m.DataContentType = headers["datacontenttype"]
+	return m
+}
+
+// Subscribe consumes the topic until ctx is cancelled. A message being
+// handled when ctx is cancelled is finished.
+func (b *PostgresBroker) Subscribe(ctx context.Context, topic string, handler func(ctx context.Context, message []byte) error) error {
+	wake := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+	if b.wakers[topic] == nil {
+		b.wakers[topic] = make(map[chan struct{}]struct{})
+	}
+	b.wakers[topic][wake] = struct{}{}
+
+	b.subscribers.Add(1)
+	go func() {
+		defer b.subscribers.Done()
+		defer b.removeWaker(topic, wake)
+		b.consume(ctx, topic, wake, handler)
+	}()
+	return nil
+}
+
+func (b *PostgresBroker) removeWaker(topic string, wake chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.wakers[topic], wake)
+}
+
+// consume handles the topic's messages one at a time, and waits for a
+// notification or the poll interval whenever none is visible.
+func (b *PostgresBroker) consume(ctx context.Context, topic string, wake <-chan struct{}, handler func(ctx context.Context, message []byte) error) {
+	ticker := time.NewTicker(b.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil && !b.stopping() {
+			handled, err := b.handleNext(ctx, topic, handler)
+			if err != nil {
+				log.Printf("Failed to consume %s: %v", topic, err)
+				break
+			}
+			if !handled {
+				break
+			}
+		}
+
+		select {
+		case <-wake:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *PostgresBroker) stopping() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+type claimedMessage struct {
+	ID       int64  `db:"id"`
+	Payload  []byte `db:"payload"`
+	Headers  []byte `db:"headers"`
+	Attempts int    `db:"attempts"`
+}
+
+// handleNext claims the oldest visible message of the topic and handles it.
+// It reports whether there was one.
+func (b *PostgresBroker) handleNext(ctx context.Context, topic string, handler func(ctx context.Context, message []byte) error) (bool, error) {
+	const claim = `
+		UPDATE broker_messages
+		SET attempts = attempts + 1,
+			visible_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE id = (
+			SELECT id FROM broker_messages
+			WHERE topic = $1 AND dead_at IS NULL AND visible_at <= CURRENT_TIMESTAMP
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, payload, headers, attempts`
+
+	var msg claimedMessage
+	err := b.db.QueryRowxContext(ctx, claim, topic, b.visibility.Seconds()).StructScan(&msg)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Settle the message even if the subscription is cancelled meanwhile
+	ctx = envelope.NewContext(context.WithoutCancel(ctx), topic, decode(msg.Headers))
+
+	// Its subscriber died or timed out on every attempt
+	if msg.Attempts > b.retry.MaxRetries+1 {
+		return true, b.deadLetter(ctx, topic, msg, errors.New("visibility timeout exceeded"))
+	}
+
+	handlerCtx, cancel := context.WithTimeout(ctx, b.visibility)
+	handlerErr := handler(handlerCtx, msg.Payload)
+	cancel()
+
+	if handlerErr == nil {
+		_, err := b.db.ExecContext(ctx, `DELETE FROM broker_messages WHERE id = $1`, msg.ID)
+		return true, err
+	}
+
+	if msg.Attempts > b.retry.MaxRetries {
+		return true, b.deadLetter(ctx, topic, msg, handlerErr)
+	}
+
+	const retry = `
+		UPDATE broker_messages
+		SET visible_at = CURRENT_TIMESTAMP + make_interval(secs => $2), last_error = $3
+		WHERE id = $1`
+	_, err = b.db.ExecContext(ctx, retry, msg.ID, b.retry.Delay(msg.Attempts).Seconds(), handlerErr.Error())
+	return true, err
+}
+
+func (b *PostgresBroker) deadLetter(ctx context.Context, topic string, msg claimedMessage, reason error) error {
+	log.Printf("Dead-lettering message %d on %s after %d attempts: %v", msg.ID, topic, msg.Attempts, reason)
+
+	const query = `
+		UPDATE broker_messages SET dead_at = CURRENT_TIMESTAMP, last_error = $2
+		WHERE id = $1`
+	_, err := b.db.ExecContext(ctx, query, msg.ID, reason.Error())
+	return err
+}
+
+// listen wakes the subscribers of every notified topic until the broker is
+// closed, re-listening after connection failures.
+func (b *PostgresBroker) listen() {
+	defer b.subscribers.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-b.done
+		cancel()
+	}()
+
+	for ctx.Err() == nil {
+		err := b.listenOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Failed to listen for broker messages: %v", err)
+
+		select {
+		case <-time.After(b.pollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *PostgresBroker) listenOnce(ctx context.Context) error {
+	conn, err := b.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+
+	// Notifications may have been missed while not listening
+	b.wakeAll()
+
+	return conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("listening needs the pgx driver, got %T", driverConn)
+		}
+		for {
+			n, err := pgxConn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			b.wake(n.Payload)
+		}
+	})
+}
+
+func (b *PostgresBroker) wake(topic string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for wake := range b.wakers[topic] {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *PostgresBroker) wakeAll() {
+	b.mu.Lock()
+	topics := make([]string, 0, len(b.wakers))
+	for topic := range b.wakers {
+		topics = append(topics, topic)
+	}
+	b.mu.Unlock()
+
+	for _, topic := range topics {
+		b.wake(topic)
+	}
+}
+
+// DeadLetter is a message that kept failing.
+type DeadLetter struct {
+	ID        int64     `db:"id" json:"id"`
+	Topic     string    `db:"topic" json:"topic"`
+	Attempts  int       `db:"attempts" json:"attempts"`
+	LastError string    `db:"last_error" json:"lastError"`
+	DeadAt    time.Time `db:"dead_at" json:"deadAt"`
+	Payload   []byte    `db:"payload" json:"payload"`
+}
+
+// DeadLetters returns up to limit of the topic's dead-lettered messages,
+// oldest first.
+func (b *PostgresBroker) DeadLetters(ctx context.Context, topic string, limit int) ([]DeadLetter, error) {
+	const query = `
+		SELECT id, topic, attempts, COALESCE(last_error, '') AS last_error, dead_at, payload
+		FROM broker_messages
+		WHERE topic = $1 AND dead_at IS NOT NULL
+		ORDER BY id
+		LIMIT $2`
+	var letters []DeadLetter
+	if err := b.db.SelectContext(ctx, &letters, query, topic, limit); err != nil {
+		return nil, err
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetters makes up to limit of the topic's dead-lettered messages
+// visible again with a fresh retry budget, and returns how many it replayed.
+func (b *PostgresBroker) ReplayDeadLetters(ctx context.Context, topic string, limit int) (int, error) {
+	const query = `
+		WITH replayed AS (
+			UPDATE broker_messages
+			SET dead_at = NULL, attempts = 0, visible_at = CURRENT_TIMESTAMP
+			WHERE id IN (
+				SELECT id FROM broker_messages
+				WHERE topic = $1 AND dead_at IS NOT NULL
+				ORDER BY id
+				LIMIT $2
+			)
+			RETURNING id
+		)
+		SELECT COUNT(*) FROM replayed`
+	var replayed int
+	if err := b.db.QueryRowContext(ctx, query, topic, limit).Scan(&replayed); err != nil {
+		return 0, err
+	}
+	if replayed > 0 {
+		if _, err := b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, topic); err != nil {
+			return replayed, err
+		}
+	}
+	return replayed, nil
+}
+
+// Close stops every subscription and waits for in-flight messages to be
+// handled. It does not close the database.
+func (b *PostgresBroker) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	b.mu.Unlock()
+
+	b.subscribers.Wait()
+	return nil
+}
@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"midaslabs/sdk/brokertest"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// TestConformance runs against the database at POSTGRES_TEST_URL, which must
+// be migrated with db/auth/migrations.
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_URL")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_URL not set")
+	}
+
+	db, err := sqlx.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	brokertest.Run(t, func(t *testing.T) brokertest.Broker {
+		return NewPostgresBroker(db, WithRetryPolicy(RetryPolicy{
+			MaxRetries: brokertest.MaxRetries,
+			BaseDelay:  10 * time.Millisecond,
+			MaxDelay:   brokertest.MaxRetryDelay,
+		}), WithPollInterval(50*time.Millisecond))
+	})
+}
@@ -0,0 +1,180 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	// ErrPublishNacked is returned when the broker refuses responsibility for
+	// a message.
+	ErrPublishNacked = errors.New("rabbitmq: publish nacked by broker")
+	// ErrUnroutable is returned when a mandatory message reached no queue.
+	ErrUnroutable = errors.New("rabbitmq: message unroutable")
+)
+
+// headerPublishTag identifies a publish in the message returned for it.
+const headerPublishTag = "x-publish-tag"
+
+// confirmPublisher publishes on a channel in confirm mode and matches the
+// broker's acks, nacks and returns to the publishes awaiting them.
+type confirmPublisher struct {
+	ch *amqp.Channel
+
+	mu      sync.Mutex
+	nextTag uint64
+	pending map[uint64]*pendingPublish
+}
+
+type pendingPublish struct {
+	done     chan error
+	returned *amqp.Return
+}
+
+// newConfirmPublisher puts ch into confirm mode.
+func newConfirmPublisher(ch *amqp.Channel) (*confirmPublisher, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, err
+	}
+
+	p := &confirmPublisher{
+		ch:      ch,
+		pending: make(map[uint64]*pendingPublish),
+	}
+	confirms := ch.NotifyPublish(make(chan amqp.Confirmation, 64))
+	returns := ch.NotifyReturn(make(chan amqp.Return, 64))
+	go p.dispatch(confirms, returns)
+	return p, nil
+}
+
+// publish sends a mandatory message to the queue named topic and waits for
+// the broker to confirm it, until ctx is done.
+func (p *confirmPublisher) publish(ctx context.Context, topic string, msg amqp.Publishing) error {
+	return p.publishTo(ctx, "", topic, true, msg)
+}
+
+// publishTo sends a message to an exchange and waits for the broker to
+// confirm it, until ctx is done. Only mandatory messages report
+// ErrUnroutable.
+func (p *confirmPublisher) publishTo(ctx context.Context, exchange, routingKey string, mandatory bool, msg amqp.Publishing) error {
+	p.mu.Lock()
+	p.nextTag++
+	tag := p.nextTag
+	headers := amqp.Table{headerPublishTag: strconv.FormatUint(tag, 10)}
+	for k, v := range msg.Headers {
+		if k != headerPublishTag {
+			headers[k] = v
+		}
+	}
+	msg.Headers = headers
+	pending := &pendingPublish{done: make(chan error, 1)}
+	p.pending[tag] = pending
+
+	err := p.ch.Publish(
+		exchange,
+		routingKey,
+		mandatory,
+		false, // immediate
+		msg)
+	if err != nil {
+		delete(p.pending, tag)
+		p.mu.Unlock()
+		return unavailable(err)
+	}
+	p.mu.Unlock()
+
+	select {
+	case err := <-pending.done:
+		return err
+	case <-ctx.Done():
+		p.mu.Lock()
+		delete(p.pending, tag)
+		p.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// dispatch resolves pending publishes until the channel closes, then fails
+// the rest with ErrBrokerUnavailable.
+func (p *confirmPublisher) dispatch(confirms <-chan amqp.Confirmation, returns <-chan amqp.Return) {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			p.handleReturn(ret)
+		case confirmation, ok := <-confirms:
+			if !ok {
+				p.failAll()
+				return
+			}
+			// The broker sends a message's return before its ack
+			p.drainReturns(returns)
+			p.handleConfirm(confirmation)
+		}
+	}
+}
+
+func (p *confirmPublisher) drainReturns(returns <-chan amqp.Return) {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return
+			}
+			p.handleReturn(ret)
+		default:
+			return
+		}
+	}
+}
+
+func (p *confirmPublisher) handleReturn(ret amqp.Return) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id, _ := ret.Headers[headerPublishTag].(string)
+	tag, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return
+	}
+	if pending, ok := p.pending[tag]; ok {
+		pending.returned = &ret
+	}
+}
+
+func (p *confirmPublisher) handleConfirm(confirmation amqp.Confirmation) {
+	p.mu.Lock()
+	pending, ok := p.pending[confirmation.DeliveryTag]
+	delete(p.pending, confirmation.DeliveryTag)
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	switch {
+	case !confirmation.Ack:
+		pending.done <- ErrPublishNacked
+	case pending.returned != nil:
+		pending.done <- fmt.Errorf("%w: %d %s", ErrUnroutable, pending.returned.ReplyCode, pending.returned.ReplyText)
+	default:
+		pending.done <- nil
+	}
+}
+
+func (p *confirmPublisher) failAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for tag, pending := range p.pending {
+		pending.done <- ErrBrokerUnavailable
+		delete(p.pending, tag)
+	}
+}
@@ -0,0 +1,45 @@
+package rabbitmq
+
+import (
+	"strings"
+
+	"github.com/streadway/amqp"
+)
+
+type exchangeRoute struct {
+	prefix   string
+	exchange string
+}
+
+// WithExchange publishes the topics starting with prefix to a durable topic
+// exchange, with the topic as routing key, instead of to a queue of their
+// own. Consumers bind queues of their own to the exchange through
+// SubscribeOptions, so every interested service receives each event.
+func WithExchange(exchange, prefix string) Option {
+	return func(r *RabbitMQBroker) {
+		r.exchanges = append(r.exchanges, exchangeRoute{prefix: prefix, exchange: exchange})
+	}
+}
+
+// exchangeFor returns the exchange the topic is published to, or "" for the
+// topic's own queue.
+func (r *RabbitMQBroker) exchangeFor(topic string) string {
+	for _, route := range r.exchanges {
+		if strings.HasPrefix(topic, route.prefix) {
+			return route.exchange
+		}
+	}
+	return ""
+}
+
+func declareExchange(ch *amqp.Channel, name string) error {
+	return ch.ExchangeDeclare(
+		name,    // name
+		"topic", // kind
+		true,    // durable
+		false,   // auto-delete
+		false,   // internal
+		false,   // no-wait
+		nil,     // arguments
+	)
+}
@@ -2,113 +2,452 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"midaslabs/sdk/envelope"
 
 	"github.com/streadway/amqp"
 )
 
+// ErrBrokerUnavailable is returned by Publish while the connection to
+// RabbitMQ is down, or when it drops before the broker confirmed a message.
+// Publishes are not buffered; callers retry.
+var ErrBrokerUnavailable = errors.New("rabbitmq: broker unavailable")
+
+const (
+	minReconnectDelay = 500 * time.Millisecond
+	maxReconnectDelay = 30 * time.Second
+)
+
+// headerPrefix names the headers carrying CloudEvents attributes, following
+// the AMQP binding.
+const headerPrefix = "cloudEvents:"
+
+// RabbitMQBroker publishes to and consumes from durable queues named after
+// topics. It supervises its connection: when the connection or channel
+// closes, it reconnects with exponential backoff and jitter, re-declares the
+// queues and restores every active subscription.
+//
+// Publish waits for the broker to confirm that it took responsibility for
+// the message, and reports messages that reached no queue. A handler error
+// schedules the message for a retry under the broker's RetryPolicy.
+//
+// Messages carry an envelope: CloudEvents attributes in headers, with the
+// payload as the body. Handlers read the attributes with envelope.FromContext.
 type RabbitMQBroker struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	dsn  string
+	done chan struct{}
+
+	mu        sync.Mutex
+	conn      *amqp.Connection
+	channel   *amqp.Channel
+	publisher *confirmPublisher
+	subs      map[*subscription]struct{}
+	closed    bool
+
+	retry     RetryPolicy
+	subOpts   SubscribeOptions
+	source    string
+	exchanges []exchangeRoute
+	workers   sync.WaitGroup
+}
+
+type subscription struct {
+	ctx     context.Context
+	topic   string
+	tag     string
+	handler func(ctx context.Context, message []byte) error
+	opts    SubscribeOptions
 }
 
-func NewRabbitMQBroker(dsn string) (*RabbitMQBroker, error) {
-	conn, err := amqp.Dial(dsn)
+var consumerSeq atomic.Uint64
+
+func NewRabbitMQBroker(dsn string, opts ...Option) (*RabbitMQBroker, error) {
+	r := &RabbitMQBroker{
+		dsn:     dsn,
+		done:    make(chan struct{}),
+		subs:    make(map[*subscription]struct{}),
+		retry:   DefaultRetryPolicy,
+		subOpts: DefaultSubscribeOptions,
+		source:  envelope.DefaultSource,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	conn, ch, publisher, err := r.dial()
 	if err != nil {
 		return nil, err
 	}
+	r.conn, r.channel, r.publisher = conn, ch, publisher
+
+	go r.supervise(conn, ch)
+	return r, nil
+}
+
+func (r *RabbitMQBroker) dial() (*amqp.Connection, *amqp.Channel, *confirmPublisher, error) {
+	conn, err := amqp.Dial(r.dsn)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	ch, err := conn.Channel()
 	if err != nil {
-		return nil, err
+		conn.Close()
+		return nil, nil, nil, err
 	}
 
-	return &RabbitMQBroker{
-		conn:    conn,
-		channel: ch,
-	}, nil
+	publisher, err := newConfirmPublisher(ch)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	return conn, ch, publisher, nil
 }
 
-func (r *RabbitMQBroker) Publish(ctx context.Context, topic string, message []byte) error {
-	_, err := r.channel.QueueDeclare(
-		topic, // queue name
-		true,  // durable
-		false, // auto-delete
-		false, // exclusive
-		false, // no-wait
-		nil,   // arguments
-	)
-	if err != nil {
-		return err
+// supervise waits for the connection or its channel to close and replaces
+// both, until the broker is closed. The reconnect backoff carries over from
+// one reconnect to the next until subscriptions are restored, so a channel
+// that fails right after every dial is not redialled at the minimum delay.
+func (r *RabbitMQBroker) supervise(conn *amqp.Connection, ch *amqp.Channel) {
+	delay := minReconnectDelay
+	for {
+		connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+		select {
+		case <-r.done:
+			return
+		case err := <-connClosed:
+			log.Printf("RabbitMQ connection closed: %v", err)
+		case err := <-chClosed:
+			log.Printf("RabbitMQ channel closed: %v", err)
+			conn.Close()
+		}
+
+		r.mu.Lock()
+		r.conn, r.channel, r.publisher = nil, nil, nil
+		r.mu.Unlock()
+
+		if conn, ch, delay = r.reconnect(delay); conn == nil {
+			return
+		}
+	}
+}
+
+// reconnect dials, backing off from delay, until it succeeds or the broker
+// is closed, in which case it returns nil. Subscriptions are restored on the
+// new channel. It returns the delay to start the next reconnect from: the
+// minimum once every subscription is restored, or the grown delay otherwise.
+func (r *RabbitMQBroker) reconnect(delay time.Duration) (*amqp.Connection, *amqp.Channel, time.Duration) {
+	for {
+		select {
+		case <-r.done:
+			return nil, nil, delay
+		case <-time.After(jitter(delay)):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+
+		conn, ch, publisher, err := r.dial()
+		if err != nil {
+			log.Printf("Failed to reconnect to RabbitMQ: %v", err)
+			continue
+		}
+
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			conn.Close()
+			return nil, nil, delay
+		}
+		r.conn, r.channel, r.publisher = conn, ch, publisher
+		restored := true
+		for sub := range r.subs {
+			// A failure closes the channel, which starts another reconnect
+			if err := r.consume(ch, sub); err != nil {
+				log.Printf("Failed to restore subscription to %s: %v", sub.topic, err)
+				restored = false
+			}
+		}
+		r.mu.Unlock()
+
+		log.Println("Reconnected to RabbitMQ")
+		if restored {
+			delay = minReconnectDelay
+		}
+		return conn, ch, delay
 	}
+}
 
-	return r.channel.Publish(
-		"",    // exchange
-		topic, // routing key
-		false, // mandatory
-		false, // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        message,
-		})
+// jitter spreads reconnects over the second half of the delay, so instances
+// dropped together do not reconnect together.
+func jitter(delay time.Duration) time.Duration {
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
 }
 
-func (r *RabbitMQBroker) Subscribe(ctx context.Context, topic string, handler func(ctx context.Context, message []byte)) error {
-	_, err := r.channel.QueueDeclare(
-		topic, // queue name
+// current returns the live channel and its publisher, or nils during an
+// outage.
+func (r *RabbitMQBroker) current() (*amqp.Channel, *confirmPublisher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.channel, r.publisher
+}
+
+func declareQueue(ch *amqp.Channel, name string, args amqp.Table) error {
+	_, err := ch.QueueDeclare(
+		name,  // queue name
 		true,  // durable
 		false, // auto-delete
 		false, // exclusive
 		false, // no-wait
-		nil,   // arguments
+		args,  // arguments
 	)
-	if err != nil {
+	return err
+}
+
+// Publish sends the message to the topic's queue and waits, within the
+// context's deadline, until the broker confirms it. Messages are persistent.
+// The envelope takes the attributes set with envelope.WithPublishMetadata,
+// completed by envelope.PublishMetadata.
+func (r *RabbitMQBroker) Publish(ctx context.Context, topic string, message []byte) error {
+	ch, publisher := r.current()
+	if ch == nil {
+		return ErrBrokerUnavailable
+	}
+
+	msg := encode(envelope.PublishMetadata(ctx, topic, r.source))
+	msg.Body = message
+
+	if exchange := r.exchangeFor(topic); exchange != "" {
+		if err := declareExchange(ch, exchange); err != nil {
+			return unavailable(err)
+		}
+		// An event no one has bound a queue to yet is not an error
+		return publisher.publishTo(ctx, exchange, topic, false, msg)
+	}
+
+	if err := declareQueue(ch, topic, nil); err != nil {
+		return unavailable(err)
+	}
+	return publisher.publish(ctx, topic, msg)
+}
+
+// encode maps the attributes onto a persistent message in binary mode. An
+// expiry becomes the message's TTL.
+func encode(m envelope.Metadata) amqp.Publishing {
+	headers := amqp.Table{}
+	for k, v := range m.Headers(headerPrefix) {
+		headers[k] = v
+	}
+	msg := amqp.Publishing{
+		Headers:      headers,
+		ContentType:  m.DataContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    m.ID,
+		Timestamp:    m.Time,
+		Type:         m.Type,
+	}
+	if !m.Expiry.IsZero() {
+		ttl := max(time.Until(m.Expiry).Milliseconds(), 0)
+		msg.Expiration = strconv.FormatInt(ttl, 10)
+	}
+	return msg
+}
+
+// decode reads the attributes of a delivery. Messages published without an
+// envelope get what the AMQP properties tell.
+func decode(d amqp.Delivery) envelope.Metadata {
+	headers := make(map[string]string, len(d.Headers))
+	for k, v := range d.Headers {
+		if s, ok := v.(string); ok {
+			headers[k] = s
+		}
+	}
+	m := envelope.FromHeaders(headerPrefix, headers)
+	m.DataContentType = d.ContentType
+	if m.ID == "" {
+		m.ID = d.MessageId
+	}
+	if m.Type == "" {
+		m.Type = d.Type
+	}
+	if m.Time.IsZero() {
+		m.Time = d.Timestamp
+	}
+	return m
+}
+
+// unavailable reports errors caused by a closed connection or channel as
+// ErrBrokerUnavailable.
+func unavailable(err error) error {
+	if errors.Is(err, amqp.ErrClosed) {
+		return fmt.Errorf("%w: %v", ErrBrokerUnavailable, err)
+	}
+	return err
+}
+
+// Subscribe consumes the topic's queue until ctx is cancelled, with the
+// broker's default SubscribeOptions. Subscriptions made during an outage
+// start once the connection is restored. Messages whose handler returns an
+// error are retried, then parked in the dead-letter queue.
+func (r *RabbitMQBroker) Subscribe(ctx context.Context, topic string, handler func(ctx context.Context, message []byte) error) error {
+	return r.SubscribeWithOptions(ctx, topic, handler, r.subOpts)
+}
+
+// SubscribeWithOptions is Subscribe with its own prefetch, workers and
+// handler timeout.
+func (r *RabbitMQBroker) SubscribeWithOptions(ctx context.Context, topic string, handler func(ctx context.Context, message []byte) error, opts SubscribeOptions) error {
+	sub := &subscription{
+		ctx:     ctx,
+		topic:   topic,
+		tag:     fmt.Sprintf("%s-%d", topic, consumerSeq.Add(1)),
+		handler: handler,
+		opts:    opts.withDefaults(),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrBrokerUnavailable
+	}
+	if r.channel != nil {
+		if err := r.consume(r.channel, sub); err != nil {
+			return err
+		}
+	}
+	r.subs[sub] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.unsubscribe(sub)
+		case <-r.done:
+		}
+	}()
+	return nil
+}
+
+func (r *RabbitMQBroker) unsubscribe(sub *subscription) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.subs, sub)
+	if r.channel != nil {
+		if err := r.channel.Cancel(sub.tag, false); err != nil {
+			log.Printf("Failed to cancel consumer %s: %v", sub.tag, err)
+		}
+	}
+}
+
+// consume starts delivering the subscription's queue to its workers on ch.
+// Delivery stops when the channel closes or the subscription is cancelled.
+// It is called with r.mu held.
+func (r *RabbitMQBroker) consume(ch *amqp.Channel, sub *subscription) error {
+	if err := r.declareTopology(ch, sub.topic); err != nil {
 		return err
 	}
+	if sub.opts.Exchange != "" {
+		if err := declareExchange(ch, sub.opts.Exchange); err != nil {
+			return err
+		}
+		for _, key := range sub.opts.BindingKeys {
+			if err := ch.QueueBind(sub.topic, key, sub.opts.Exchange, false, nil); err != nil {
+				return err
+			}
+		}
+	}
 
-	msgs, err := r.channel.Consume(
-		topic, // queue
-		"",    // consumer
-		false, // auto-ack (set to false to manually ack messages)
-		false, // exclusive
-		false, // no-local
-		false, // no-wait
-		nil,   // arguments
+	// Applies to the consumer started next on the channel
+	if err := ch.Qos(sub.opts.Prefetch, 0, false); err != nil {
+		return err
+	}
+
+	msgs, err := ch.Consume(
+		sub.topic, // queue
+		sub.tag,   // consumer
+		false,     // auto-ack (set to false to manually ack messages)
+		false,     // exclusive
+		false,     // no-local
+		false,     // no-wait
+		nil,       // arguments
 	)
 	if err != nil {
 		return err
 	}
 
-	go func() {
-		for {
-			select {
-			case d, ok := <-msgs:
-				if !ok {
-					return
-				}
-
-				// Pass context to the handler
-				handler(ctx, d.Body)
-
-				// Acknowledge the message after handling
-				if err := d.Ack(false); err != nil {
-					// Log the error and handle it appropriately
-					log.Printf("Failed to acknowledge message: %v", err)
-				}
-			case <-ctx.Done():
-				// Handle context cancellation (e.g., log, clean up)
-				log.Println("Context cancelled, stopping message consumption")
-				return
+	for i := 0; i < sub.opts.Workers; i++ {
+		r.workers.Add(1)
+		go r.work(sub, msgs)
+	}
+	return nil
+}
+
+// work handles deliveries until the consumer is cancelled or the channel
+// closes, either of which closes msgs. Deliveries prefetched but not started
+// once the subscription or broker is stopping are returned to the queue.
+func (r *RabbitMQBroker) work(sub *subscription, msgs <-chan amqp.Delivery) {
+	defer r.workers.Done()
+
+	for d := range msgs {
+		if r.stopping(sub) {
+			if err := d.Nack(false, true); err != nil {
+				log.Printf("Failed to requeue message: %v", err)
 			}
+			continue
 		}
-	}()
+		r.handleDelivery(sub, d)
+	}
+}
 
-	return nil
+func (r *RabbitMQBroker) stopping(sub *subscription) bool {
+	select {
+	case <-sub.ctx.Done():
+		return true
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
 }
 
+// Close stops every consumer, waits for in-flight messages to be handled and
+// closes the connection.
 func (r *RabbitMQBroker) Close() error {
-	if err := r.channel.Close(); err != nil {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+
+	conn, ch := r.conn, r.channel
+	if ch != nil {
+		for sub := range r.subs {
+			if err := ch.Cancel(sub.tag, false); err != nil {
+				log.Printf("Failed to cancel consumer %s: %v", sub.tag, err)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	// Handlers may still publish retries through the channel
+	r.workers.Wait()
+
+	if conn == nil {
+		return nil
+	}
+	if err := ch.Close(); err != nil {
 		return err
 	}
-	return r.conn.Close()
+	return conn.Close()
 }
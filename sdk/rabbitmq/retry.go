@@ -0,0 +1,273 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"midaslabs/sdk/envelope"
+
+	"github.com/streadway/amqp"
+)
+
+// RetryPolicy bounds the redelivery of messages whose handler failed. Failed
+// messages wait in a delay queue per retry, whose TTL dead-letters them back
+// onto the topic's queue. Messages still failing after MaxRetries are parked
+// in the topic's dead-letter queue.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  time.Second,
+	MaxDelay:   time.Minute,
+}
+
+// Delay returns how long the given retry, counted from 1, waits.
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// retryPublishTimeout bounds the wait for the broker to confirm a retry.
+const retryPublishTimeout = 10 * time.Second
+
+// Option configures a RabbitMQBroker.
+type Option func(*RabbitMQBroker)
+
+// WithSource sets the CloudEvents source of the messages the broker
+// publishes, for example "/midaslabs/auth", instead of
+// envelope.DefaultSource.
+func WithSource(source string) Option {
+	return func(r *RabbitMQBroker) {
+		r.source = source
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy. Changing the delays of an
+// existing deployment requires deleting its retry queues, as RabbitMQ refuses
+// to redeclare a queue with a different TTL.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(r *RabbitMQBroker) {
+		r.retry = policy
+	}
+}
+
+// Headers describing a message's failures.
+const (
+	headerRetries       = "x-retries"
+	headerLastError     = "x-last-error"
+	headerOriginalTopic = "x-original-topic"
+)
+
+// RetryQueue names the delay queue of the given retry of a topic.
+func RetryQueue(topic string, retry int) string {
+	return fmt.Sprintf("%s.retry.%d", topic, retry)
+}
+
+// DeadLetterQueue names the queue parking a topic's failed messages.
+func DeadLetterQueue(topic string) string {
+	return topic + ".dlq"
+}
+
+// declareTopology declares the topic's queue, its retry delay queues and its
+// dead-letter queue.
+func (r *RabbitMQBroker) declareTopology(ch *amqp.Channel, topic string) error {
+	if err := declareQueue(ch, topic, nil); err != nil {
+		return err
+	}
+	for retry := 1; retry <= r.retry.MaxRetries; retry++ {
+		err := declareQueue(ch, RetryQueue(topic, retry), amqp.Table{
+			"x-message-ttl":             int32(r.retry.Delay(retry).Milliseconds()),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": topic,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return declareQueue(ch, DeadLetterQueue(topic), nil)
+}
+
+// handleDelivery runs the handler and acks the delivery once it succeeded or
+// was handed to a retry or dead-letter queue. The handler's context is not
+// cancelled with the subscription, so a message started before a shutdown is
+// finished, but it expires after the subscription's HandlerTimeout.
+func (r *RabbitMQBroker) handleDelivery(sub *subscription, d amqp.Delivery) {
+	ctx := envelope.NewContext(context.WithoutCancel(sub.ctx), sub.topic, decode(d))
+	handlerCtx, cancel := ctx, context.CancelFunc(func() {})
+	if sub.opts.HandlerTimeout > 0 {
+		handlerCtx, cancel = context.WithTimeout(ctx, sub.opts.HandlerTimeout)
+	}
+	handlerErr := sub.handler(handlerCtx, d.Body)
+	cancel()
+
+	if handlerErr != nil {
+		ctx, cancel := context.WithTimeout(ctx, retryPublishTimeout)
+		defer cancel()
+		if err := r.retryLater(ctx, sub.topic, d, handlerErr); err != nil {
+			log.Printf("Failed to schedule retry of message on %s: %v", sub.topic, err)
+			if err := d.Nack(false, true); err != nil {
+				log.Printf("Failed to requeue message: %v", err)
+			}
+			return
+		}
+	}
+
+	// Acknowledge the message after handling
+	if err := d.Ack(false); err != nil {
+		// Log the error and handle it appropriately
+		log.Printf("Failed to acknowledge message: %v", err)
+	}
+}
+
+// retryLater republishes a failed delivery to its next retry queue, or to the
+// dead-letter queue once its retries are exhausted.
+func (r *RabbitMQBroker) retryLater(ctx context.Context, topic string, d amqp.Delivery, handlerErr error) error {
+	retries := retriesOf(d.Headers) + 1
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[headerRetries] = int32(retries)
+	headers[headerLastError] = handlerErr.Error()
+
+	queue := RetryQueue(topic, retries)
+	if retries > r.retry.MaxRetries {
+		queue = DeadLetterQueue(topic)
+		headers[headerOriginalTopic] = topic
+		log.Printf("Parking message on %s after %d failed attempts: %v", queue, retries, handlerErr)
+	}
+
+	_, publisher := r.current()
+	if publisher == nil {
+		return ErrBrokerUnavailable
+	}
+	return publisher.publish(ctx, queue, republish(d, headers))
+}
+
+// republish copies a delivery, with its envelope, under new headers.
+func republish(d amqp.Delivery, headers amqp.Table) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Timestamp:    d.Timestamp,
+		Type:         d.Type,
+		Body:         d.Body,
+	}
+}
+
+func retriesOf(headers amqp.Table) int {
+	switch n := headers[headerRetries].(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+// DeadLetter is a message parked in a dead-letter queue.
+type DeadLetter struct {
+	Topic     string `json:"topic"`
+	Retries   int    `json:"retries"`
+	LastError string `json:"lastError"`
+	Body      string `json:"body"`
+}
+
+// InspectDeadLetters returns up to limit messages of the topic's dead-letter
+// queue without removing them.
+func (r *RabbitMQBroker) InspectDeadLetters(ctx context.Context, topic string, limit int) ([]DeadLetter, error) {
+	ch, err := r.openChannel()
+	if err != nil {
+		return nil, err
+	}
+	// Closing the channel returns every message taken to the queue
+	defer ch.Close()
+
+	var letters []DeadLetter
+	for len(letters) < limit && ctx.Err() == nil {
+		d, ok, err := ch.Get(DeadLetterQueue(topic), false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		lastError, _ := d.Headers[headerLastError].(string)
+		letters = append(letters, DeadLetter{
+			Topic:     topic,
+			Retries:   retriesOf(d.Headers),
+			LastError: lastError,
+			Body:      string(d.Body),
+		})
+	}
+	return letters, ctx.Err()
+}
+
+// ReplayDeadLetters moves up to limit messages from the topic's dead-letter
+// queue back onto the topic with a fresh retry budget, and returns how many
+// it moved.
+func (r *RabbitMQBroker) ReplayDeadLetters(ctx context.Context, topic string, limit int) (int, error) {
+	ch, err := r.openChannel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	replayed := 0
+	for replayed < limit && ctx.Err() == nil {
+		d, ok, err := ch.Get(DeadLetterQueue(topic), false)
+		if err != nil {
+			return replayed, err
+		}
+		if !ok {
+			break
+		}
+
+		headers := amqp.Table{}
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		delete(headers, headerRetries)
+		delete(headers, headerLastError)
+		delete(headers, headerOriginalTopic)
+
+		_, publisher := r.current()
+		if publisher == nil {
+			return replayed, ErrBrokerUnavailable
+		}
+		// Keeps the envelope, without the failure headers
+		if err := publisher.publish(ctx, topic, republish(d, headers)); err != nil {
+			return replayed, err
+		}
+		if err := d.Ack(false); err != nil {
+			return replayed, err
+		}
+		replayed++
+	}
+	return replayed, ctx.Err()
+}
+
+// openChannel opens a channel of its own on the live connection.
+func (r *RabbitMQBroker) openChannel() (*amqp.Channel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		return nil, ErrBrokerUnavailable
+	}
+	return r.conn.Channel()
+}
@@ -0,0 +1,41 @@
+package rabbitmq
+
+import "time"
+
+// SubscribeOptions control how a subscription consumes its queue.
+type SubscribeOptions struct {
+	// Workers is the number of messages handled concurrently.
+	Workers int
+	// Prefetch is the number of unacknowledged messages the broker sends
+	// ahead; it defaults to Workers.
+	Prefetch int
+	// HandlerTimeout bounds each handler call; zero leaves it unbounded. A
+	// handler that times out fails and its message is retried.
+	HandlerTimeout time.Duration
+	// Exchange, when set, is a topic exchange the subscription's queue is
+	// bound to with each of BindingKeys, such as "user.*". The queue is named
+	// after the subscribed topic, which then names the consumer rather than
+	// the events.
+	Exchange    string
+	BindingKeys []string
+}
+
+var DefaultSubscribeOptions = SubscribeOptions{Workers: 1}
+
+func (o SubscribeOptions) withDefaults() SubscribeOptions {
+	if o.Workers < 1 {
+		o.Workers = 1
+	}
+	if o.Prefetch < 1 {
+		o.Prefetch = o.Workers
+	}
+	return o
+}
+
+// WithSubscribeOptions replaces the DefaultSubscribeOptions used by
+// Subscribe.
+func WithSubscribeOptions(opts SubscribeOptions) Option {
+	return func(r *RabbitMQBroker) {
+		r.subOpts = opts
+	}
+}